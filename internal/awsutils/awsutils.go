@@ -0,0 +1,37 @@
+// Package awsutils centralizes how the agent builds AWS SDK sessions so
+// every S3/KMS call site configures credentials, region and custom
+// endpoints the same way.
+package awsutils
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/percona/percona-backup-mongodb/internal/storage"
+	"github.com/pkg/errors"
+)
+
+// GetAWSSessionFromStorage builds an AWS session for stg. When stg.EndpointURL
+// is set, the session points at that endpoint instead of AWS S3, which is
+// how S3-compatible backends (MinIO, Ceph, Wasabi) are supported.
+func GetAWSSessionFromStorage(stg storage.S3) (*session.Session, error) {
+	cfg := aws.NewConfig().WithRegion(stg.Region)
+
+	if stg.Credentials.AccessKeyID != "" {
+		cfg = cfg.WithCredentials(credentials.NewStaticCredentials(
+			stg.Credentials.AccessKeyID, stg.Credentials.SecretAccessKey, ""))
+	}
+
+	if stg.EndpointURL != "" {
+		cfg = cfg.
+			WithEndpoint(stg.EndpointURL).
+			WithS3ForcePathStyle(stg.ForcePathStyle).
+			WithDisableSSL(stg.DisableSSL)
+	}
+
+	sess, err := session.NewSession(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create AWS session")
+	}
+	return sess, nil
+}
@@ -0,0 +1,87 @@
+package integrity
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// ManifestSuffix is appended to a backup's object name to get the name
+// its manifest is stored under.
+const ManifestSuffix = ".manifest.json"
+
+// ShardDigest is the per-file digest recorded when a backup is split
+// across multiple storage objects. Single-file backups record exactly
+// one, covering the whole object.
+type ShardDigest struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	Digest []byte `json:"digest"`
+}
+
+// Manifest is the integrity record for one backup, written next to it
+// in the same storage target as "<name>.manifest.json".
+type Manifest struct {
+	Name             string        `json:"name"`
+	Algorithm        Algorithm     `json:"algorithm"`
+	OriginalSize     int64         `json:"originalSize"`
+	CompressedSize   int64         `json:"compressedSize"`
+	PlaintextDigest  []byte        `json:"plaintextDigest"`
+	PlaintextMerkle  []byte        `json:"plaintextMerkleRoot"`
+	CiphertextDigest []byte        `json:"ciphertextDigest"`
+	Shards           []ShardDigest `json:"shards"`
+	CreatedUnix      int64         `json:"createdUnix"`
+	Signature        []byte        `json:"signature,omitempty"`
+}
+
+// signingPayload returns the canonical bytes signed/verified, i.e. the
+// manifest as JSON with Signature cleared.
+func (m *Manifest) signingPayload() ([]byte, error) {
+	cp := *m
+	cp.Signature = nil
+	return json.Marshal(cp)
+}
+
+// Sign computes an HMAC-SHA256 over the manifest (excluding the
+// signature field itself) keyed by key and stores it in Signature.
+func (m *Manifest) Sign(key []byte) error {
+	payload, err := m.signingPayload()
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal manifest for signing")
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	m.Signature = mac.Sum(nil)
+	return nil
+}
+
+// VerifySignature reports whether Signature is a valid HMAC-SHA256 over
+// the rest of the manifest under key.
+func (m *Manifest) VerifySignature(key []byte) (bool, error) {
+	payload, err := m.signingPayload()
+	if err != nil {
+		return false, errors.Wrap(err, "cannot marshal manifest for verification")
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	expected := mac.Sum(nil)
+	return subtle.ConstantTimeCompare(expected, m.Signature) == 1, nil
+}
+
+// Marshal serializes m as indented JSON, for readability when a user
+// inspects a manifest object directly.
+func (m *Manifest) Marshal() ([]byte, error) {
+	return json.MarshalIndent(m, "", "  ")
+}
+
+// UnmarshalManifest parses a manifest previously written by Marshal.
+func UnmarshalManifest(data []byte) (*Manifest, error) {
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, errors.Wrap(err, "cannot parse manifest")
+	}
+	return &m, nil
+}
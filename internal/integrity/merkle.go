@@ -0,0 +1,36 @@
+package integrity
+
+// MerkleRoot folds a list of leaf hashes (one per ChunkSize-sized
+// plaintext chunk, in stream order) into a single root hash, using alg
+// for the interior node hash too. An odd node out at any level is
+// promoted unchanged, the common convention for non-power-of-two leaf
+// counts.
+func MerkleRoot(alg Algorithm, leaves [][]byte) ([]byte, error) {
+	if len(leaves) == 0 {
+		h, err := NewHash(alg)
+		if err != nil {
+			return nil, err
+		}
+		return h.Sum(nil), nil
+	}
+
+	level := leaves
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			h, err := NewHash(alg)
+			if err != nil {
+				return nil, err
+			}
+			h.Write(level[i])
+			h.Write(level[i+1])
+			next = append(next, h.Sum(nil))
+		}
+		level = next
+	}
+	return level[0], nil
+}
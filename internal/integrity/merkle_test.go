@@ -0,0 +1,79 @@
+package integrity
+
+import (
+	"bytes"
+	"testing"
+)
+
+func leaf(b byte) []byte {
+	h, _ := NewHash(SHA256)
+	h.Write([]byte{b})
+	return h.Sum(nil)
+}
+
+func TestMerkleRootEmpty(t *testing.T) {
+	root, err := MerkleRoot(SHA256, nil)
+	if err != nil {
+		t.Fatalf("MerkleRoot: %s", err)
+	}
+	h, _ := NewHash(SHA256)
+	if !bytes.Equal(root, h.Sum(nil)) {
+		t.Fatalf("empty input should hash to the algorithm's zero-value digest")
+	}
+}
+
+func TestMerkleRootSingleLeaf(t *testing.T) {
+	l := leaf(1)
+	root, err := MerkleRoot(SHA256, [][]byte{l})
+	if err != nil {
+		t.Fatalf("MerkleRoot: %s", err)
+	}
+	if !bytes.Equal(root, l) {
+		t.Fatalf("single leaf should be promoted unchanged to the root")
+	}
+}
+
+// TestMerkleRootOddLeafPromoted checks the documented odd-node-out
+// convention: with 3 leaves, the third is promoted unchanged to the
+// second level instead of being paired with a duplicate of itself.
+func TestMerkleRootOddLeafPromoted(t *testing.T) {
+	l0, l1, l2 := leaf(0), leaf(1), leaf(2)
+
+	root3, err := MerkleRoot(SHA256, [][]byte{l0, l1, l2})
+	if err != nil {
+		t.Fatalf("MerkleRoot(3 leaves): %s", err)
+	}
+
+	h, _ := NewHash(SHA256)
+	h.Write(l0)
+	h.Write(l1)
+	pair01 := h.Sum(nil)
+
+	root2, err := MerkleRoot(SHA256, [][]byte{pair01, l2})
+	if err != nil {
+		t.Fatalf("MerkleRoot(2 leaves): %s", err)
+	}
+
+	if !bytes.Equal(root3, root2) {
+		t.Fatalf("3-leaf root should equal hashing {hash(l0,l1), l2} with l2 promoted unchanged")
+	}
+}
+
+// TestMerkleRootOrderSensitive guards against a construction that
+// accidentally sorts or otherwise ignores leaf order, which would let
+// two backups with identical chunks in a different sequence collide.
+func TestMerkleRootOrderSensitive(t *testing.T) {
+	l0, l1 := leaf(0), leaf(1)
+
+	forward, err := MerkleRoot(SHA256, [][]byte{l0, l1})
+	if err != nil {
+		t.Fatalf("MerkleRoot: %s", err)
+	}
+	reversed, err := MerkleRoot(SHA256, [][]byte{l1, l0})
+	if err != nil {
+		t.Fatalf("MerkleRoot: %s", err)
+	}
+	if bytes.Equal(forward, reversed) {
+		t.Fatalf("MerkleRoot must be sensitive to leaf order")
+	}
+}
@@ -0,0 +1,29 @@
+package reader
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// gzipReadCloser adapts a *gzip.Reader, which only resets/closes its own
+// decompression state, to the io.ReadCloser interface the rest of the
+// reader chain expects.
+type gzipReadCloser struct {
+	*gzip.Reader
+}
+
+func newGzipReadCloser(src io.Reader) (*gzipReadCloser, error) {
+	gzr, err := gzip.NewReader(src)
+	if err != nil {
+		return nil, err
+	}
+	return &gzipReadCloser{Reader: gzr}, nil
+}
+
+// Close only closes the gzip decompressor itself. It deliberately does
+// not close the reader gzip.NewReader was given: gzipReadCloser is one
+// link in BackupReader's reader chain, and BackupReader.Close already
+// closes every link itself, one by one, exactly like cypherReader.Close.
+func (g *gzipReadCloser) Close() error {
+	return g.Reader.Close()
+}
@@ -0,0 +1,102 @@
+// Package reader provides the restore-side counterpart to
+// internal/writer: it turns a storage object back into the plaintext
+// mongodump/mongorestore stream, undoing compression and, when present,
+// decryption.
+package reader
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/golang/snappy"
+	"github.com/percona/percona-backup-mongodb/internal/storage"
+	"github.com/percona/percona-backup-mongodb/internal/writer"
+	pb "github.com/percona/percona-backup-mongodb/proto/messages"
+	"github.com/pierrec/lz4"
+	"github.com/pkg/errors"
+)
+
+// nopCloseReader adapts an io.Reader with no Close of its own (lz4.Reader,
+// snappy.Reader) to io.ReadCloser: closing it is a no-op since it holds no
+// resource beyond the reader it decompresses, which BackupReader.Close
+// closes separately.
+type nopCloseReader struct {
+	io.Reader
+}
+
+func (nopCloseReader) Close() error { return nil }
+
+// BackupReader is the mirror image of writer.BackupWriter: a chain of
+// io.ReadClosers where reads flow from storage through decompression and
+// decryption towards the caller.
+type BackupReader struct {
+	readers []io.ReadCloser
+}
+
+func (br *BackupReader) Read(p []byte) (int, error) {
+	return br.readers[len(br.readers)-1].Read(p)
+}
+
+func (br *BackupReader) Close() error {
+	var err error
+	for i := len(br.readers) - 1; i >= 0; i-- {
+		if cerr := br.readers[i].Close(); cerr != nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// NewBackupReader opens name in stg and, if cypher is not
+// CYPHER_NO_CYPHER, wraps it with a decrypting reader using keyProvider
+// to unwrap the DEK. compressionType must match what NewBackupWriter was
+// called with for this backup.
+func NewBackupReader(name string, stg storage.Storage, compressionType pb.CompressionType,
+	cypher pb.Cypher, keyProvider writer.KeyProvider) (*BackupReader, error) {
+	br := &BackupReader{readers: []io.ReadCloser{}}
+
+	driver, err := writer.GetDriver(stg)
+	if err != nil {
+		return nil, err
+	}
+	dr, err := driver.NewReader(context.Background(), name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open %q for reading", name)
+	}
+	br.readers = append(br.readers, dr)
+
+	// NewBackupWriter compresses the plaintext before encrypting it
+	// (storage bytes are encrypt(compress(plaintext))), so undoing that
+	// has to decrypt first and decompress second, the reverse order.
+	if cypher != pb.Cypher_CYPHER_NO_CYPHER {
+		cr, err := writer.NewCypherReader(br.readers[len(br.readers)-1], writer.CypherOptions{
+			Cypher:      cypher,
+			KeyProvider: keyProvider,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot set up decryption")
+		}
+		br.readers = append(br.readers, cr)
+	}
+
+	switch compressionType {
+	case pb.CompressionType_COMPRESSION_TYPE_GZIP:
+		gzr, err := newGzipReadCloser(br.readers[len(br.readers)-1])
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot open gzip reader")
+		}
+		br.readers = append(br.readers, gzr)
+	case pb.CompressionType_COMPRESSION_TYPE_LZ4:
+		lz4r := lz4.NewReader(br.readers[len(br.readers)-1])
+		br.readers = append(br.readers, nopCloseReader{lz4r})
+	case pb.CompressionType_COMPRESSION_TYPE_SNAPPY:
+		snappyr := snappy.NewReader(br.readers[len(br.readers)-1])
+		br.readers = append(br.readers, nopCloseReader{snappyr})
+	}
+
+	if len(br.readers) == 0 {
+		return nil, fmt.Errorf("there are no backup readers")
+	}
+	return br, nil
+}
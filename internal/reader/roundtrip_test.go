@@ -0,0 +1,80 @@
+package reader_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/percona/percona-backup-mongodb/internal/reader"
+	"github.com/percona/percona-backup-mongodb/internal/storage"
+	"github.com/percona/percona-backup-mongodb/internal/writer"
+	pb "github.com/percona/percona-backup-mongodb/proto/messages"
+)
+
+// TestRoundTrip writes a backup through writer.NewBackupWriter and reads
+// it back through reader.NewBackupReader for every combination of
+// compression and cypher, to guard against the two ways these chains can
+// silently disagree: a wrapper closing what it doesn't own, and the
+// reader nesting compression/encryption in the opposite order from the
+// writer.
+func TestRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pbm-roundtrip")
+	if err != nil {
+		t.Fatalf("TempDir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	stg := storage.Storage{Type: "filesystem", Filesystem: storage.Filesystem{Path: dir}}
+	keyProvider, err := writer.NewPassphraseKeyProvider([]byte("correct horse battery staple"), nil)
+	if err != nil {
+		t.Fatalf("NewPassphraseKeyProvider: %s", err)
+	}
+
+	plaintext := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog\n"), 10000)
+
+	compressionTypes := []pb.CompressionType{
+		pb.CompressionType_COMPRESSION_TYPE_NONE,
+		pb.CompressionType_COMPRESSION_TYPE_GZIP,
+		pb.CompressionType_COMPRESSION_TYPE_LZ4,
+		pb.CompressionType_COMPRESSION_TYPE_SNAPPY,
+	}
+	cyphers := []pb.Cypher{
+		pb.Cypher_CYPHER_NO_CYPHER,
+		pb.Cypher_CYPHER_AES256_GCM,
+	}
+
+	for _, ct := range compressionTypes {
+		for _, cy := range cyphers {
+			name := ct.String() + "_" + cy.String()
+			t.Run(name, func(t *testing.T) {
+				bw, err := writer.NewBackupWriter(name, stg, &pb.BackupOptions{CompressionType: ct, Cypher: cy}, keyProvider, nil)
+				if err != nil {
+					t.Fatalf("NewBackupWriter: %s", err)
+				}
+				if _, err := bw.Write(plaintext); err != nil {
+					t.Fatalf("Write: %s", err)
+				}
+				if err := bw.Close(); err != nil {
+					t.Fatalf("Close: %s", err)
+				}
+
+				br, err := reader.NewBackupReader(name, stg, ct, cy, keyProvider)
+				if err != nil {
+					t.Fatalf("NewBackupReader: %s", err)
+				}
+				got, err := ioutil.ReadAll(br)
+				if err != nil {
+					t.Fatalf("ReadAll: %s", err)
+				}
+				if err := br.Close(); err != nil {
+					t.Fatalf("Close: %s", err)
+				}
+
+				if !bytes.Equal(got, plaintext) {
+					t.Fatalf("round trip mismatch: got %d bytes, want %d", len(got), len(plaintext))
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package scheduler
+
+import (
+	"context"
+
+	pb "github.com/percona/percona-backup-mongodb/proto/messages"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server exposes a Scheduler over gRPC so pbmctl/the coordinator UI can
+// list, pause, resume and trigger scheduled backup jobs without an
+// agent-side config reload.
+type Server struct {
+	pb.UnimplementedSchedulerServiceServer
+	scheduler *Scheduler
+}
+
+// NewServer wraps scheduler for registration against a grpc.Server.
+func NewServer(scheduler *Scheduler) *Server {
+	return &Server{scheduler: scheduler}
+}
+
+func (s *Server) ListSchedules(ctx context.Context, _ *pb.ListSchedulesRequest) (*pb.ListSchedulesResponse, error) {
+	statuses := s.scheduler.List()
+	resp := &pb.ListSchedulesResponse{
+		Schedules: make([]*pb.ScheduleStatus, 0, len(statuses)),
+	}
+	for _, st := range statuses {
+		entry := &pb.ScheduleStatus{
+			Name:    st.Name,
+			Paused:  st.Paused,
+			Running: st.Running,
+		}
+		if !st.Next.IsZero() {
+			entry.NextRunUnix = st.Next.Unix()
+		}
+		if !st.LastRun.IsZero() {
+			entry.LastRunUnix = st.LastRun.Unix()
+		}
+		if st.LastErr != nil {
+			entry.LastError = st.LastErr.Error()
+		}
+		resp.Schedules = append(resp.Schedules, entry)
+	}
+	return resp, nil
+}
+
+func (s *Server) PauseSchedule(ctx context.Context, req *pb.ScheduleNameRequest) (*pb.ScheduleActionResponse, error) {
+	if err := s.scheduler.Pause(req.Name); err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return &pb.ScheduleActionResponse{}, nil
+}
+
+func (s *Server) ResumeSchedule(ctx context.Context, req *pb.ScheduleNameRequest) (*pb.ScheduleActionResponse, error) {
+	if err := s.scheduler.Resume(req.Name); err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return &pb.ScheduleActionResponse{}, nil
+}
+
+func (s *Server) TriggerScheduleNow(ctx context.Context, req *pb.ScheduleNameRequest) (*pb.ScheduleActionResponse, error) {
+	if err := s.scheduler.TriggerNow(req.Name); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return &pb.ScheduleActionResponse{}, nil
+}
@@ -0,0 +1,150 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/percona/percona-backup-mongodb/internal/integrity"
+	"github.com/percona/percona-backup-mongodb/internal/writer"
+	"github.com/pkg/errors"
+)
+
+// GFSPolicy is a grandfather-father-son retention window: keep the most
+// recent Daily daily backups, Weekly weekly backups (one per ISO week)
+// and Monthly monthly backups (one per calendar month).
+type GFSPolicy struct {
+	Daily   int
+	Weekly  int
+	Monthly int
+}
+
+// RetentionPolicy selects which of a job's backups survive a sweep. The
+// modes are combined with logical OR: an object is kept if any
+// configured rule would keep it. A zero-value RetentionPolicy keeps
+// everything (no pruning), matching the principle of least surprise for
+// a Job that didn't opt into retention.
+type RetentionPolicy struct {
+	KeepLast          int
+	GFS               *GFSPolicy
+	MaxTotalSizeBytes int64
+}
+
+// ApplyRetention lists every object belonging to jobName in driver,
+// decides which to keep under policy, and deletes the rest. Objects are
+// attributed to a job by the "<jobName>-" name prefix written by
+// backupObjectName.
+func ApplyRetention(ctx context.Context, driver writer.StorageDriver, jobName string, policy RetentionPolicy) error {
+	if policy.KeepLast == 0 && policy.GFS == nil && policy.MaxTotalSizeBytes == 0 {
+		return nil
+	}
+
+	objects, err := driver.List(ctx)
+	if err != nil {
+		return errors.Wrap(err, "cannot list backups for retention sweep")
+	}
+
+	prefix := jobName + "-"
+	var backups []writer.ObjectInfo
+	for _, o := range objects {
+		if strings.HasPrefix(o.Name, prefix) {
+			backups = append(backups, o)
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].LastModified.After(backups[j].LastModified)
+	})
+
+	keep := make(map[string]bool, len(backups))
+	if policy.KeepLast > 0 {
+		for i := 0; i < policy.KeepLast && i < len(backups); i++ {
+			keep[backups[i].Name] = true
+		}
+	}
+	if policy.GFS != nil {
+		for name := range gfsKeep(backups, *policy.GFS) {
+			keep[name] = true
+		}
+	}
+	if policy.MaxTotalSizeBytes > 0 {
+		for name := range maxSizeKeep(backups, policy.MaxTotalSizeBytes) {
+			keep[name] = true
+		}
+	}
+
+	for _, b := range backups {
+		if keep[b.Name] {
+			continue
+		}
+		if err := driver.Delete(ctx, b.Name); err != nil {
+			return errors.Wrapf(err, "cannot delete %q outside retention window", b.Name)
+		}
+		// BackupWriter.Close writes a manifest (and, for a resumable S3
+		// upload, a checkpoint) alongside every backup; List filters both
+		// out of enumeration, so a later sweep or ScrubStorage would never
+		// see them again and they'd accumulate forever if left behind.
+		if err := driver.Delete(ctx, b.Name+integrity.ManifestSuffix); err != nil {
+			return errors.Wrapf(err, "cannot delete manifest for %q outside retention window", b.Name)
+		}
+		if err := driver.Delete(ctx, b.Name+writer.CheckpointSuffix); err != nil {
+			return errors.Wrapf(err, "cannot delete checkpoint for %q outside retention window", b.Name)
+		}
+	}
+	return nil
+}
+
+// gfsKeep returns the set of object names to keep under a
+// grandfather-father-son policy. backups must already be sorted newest
+// first.
+func gfsKeep(backups []writer.ObjectInfo, policy GFSPolicy) map[string]bool {
+	keep := map[string]bool{}
+	keepFirstPerBucket(backups, policy.Daily, keep, func(t time.Time) string {
+		return t.Format("2006-01-02")
+	})
+	keepFirstPerBucket(backups, policy.Weekly, keep, func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	})
+	keepFirstPerBucket(backups, policy.Monthly, keep, func(t time.Time) string {
+		return t.Format("2006-01")
+	})
+	return keep
+}
+
+// keepFirstPerBucket walks backups (newest first) grouping them by
+// bucketKey(LastModified) and keeps the newest backup of each of the
+// first maxBuckets distinct buckets encountered.
+func keepFirstPerBucket(backups []writer.ObjectInfo, maxBuckets int, keep map[string]bool, bucketKey func(time.Time) string) {
+	if maxBuckets <= 0 {
+		return
+	}
+	seen := map[string]bool{}
+	for _, b := range backups {
+		key := bucketKey(b.LastModified)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		keep[b.Name] = true
+		if len(seen) >= maxBuckets {
+			return
+		}
+	}
+}
+
+// maxSizeKeep keeps the newest backups whose cumulative size stays
+// within maxBytes.
+func maxSizeKeep(backups []writer.ObjectInfo, maxBytes int64) map[string]bool {
+	keep := map[string]bool{}
+	var total int64
+	for _, b := range backups {
+		if total+b.Size > maxBytes {
+			break
+		}
+		total += b.Size
+		keep[b.Name] = true
+	}
+	return keep
+}
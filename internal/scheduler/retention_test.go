@@ -0,0 +1,142 @@
+package scheduler
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/percona/percona-backup-mongodb/internal/integrity"
+	"github.com/percona/percona-backup-mongodb/internal/writer"
+)
+
+// fakeDriver is a minimal in-memory writer.StorageDriver for exercising
+// ApplyRetention's delete decisions without a real backend.
+type fakeDriver struct {
+	objects []writer.ObjectInfo
+	deleted map[string]bool
+}
+
+func (d *fakeDriver) NewWriter(context.Context, string) (io.WriteCloser, error) {
+	panic("not implemented")
+}
+func (d *fakeDriver) NewReader(context.Context, string) (io.ReadCloser, error) {
+	panic("not implemented")
+}
+func (d *fakeDriver) List(context.Context) ([]writer.ObjectInfo, error) { return d.objects, nil }
+func (d *fakeDriver) Delete(_ context.Context, name string) error {
+	if d.deleted == nil {
+		d.deleted = map[string]bool{}
+	}
+	d.deleted[name] = true
+	return nil
+}
+
+func mustParse(t *testing.T, s string) time.Time {
+	t.Helper()
+	tm, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		t.Fatalf("time.Parse(%q): %s", s, err)
+	}
+	return tm
+}
+
+// TestGfsKeepBucketing checks that gfsKeep keeps the newest backup of
+// each of the first Daily/Weekly/Monthly distinct buckets it walks,
+// across a run that spans a calendar month boundary.
+func TestGfsKeepBucketing(t *testing.T) {
+	backups := []writer.ObjectInfo{
+		{Name: "d0", LastModified: mustParse(t, "2024-03-02")},
+		{Name: "d1", LastModified: mustParse(t, "2024-03-01")},
+		{Name: "d2", LastModified: mustParse(t, "2024-02-29")},
+		{Name: "d3", LastModified: mustParse(t, "2024-02-28")},
+		{Name: "d4", LastModified: mustParse(t, "2024-02-27")},
+	}
+
+	keep := gfsKeep(backups, GFSPolicy{Daily: 2, Weekly: 1, Monthly: 2})
+
+	// Daily: 2
+	for _, want := range []string{"d0", "d1"} {
+		if !keep[want] {
+			t.Errorf("expected daily bucket to keep %q", want)
+		}
+	}
+	// Monthly: 2 distinct months (March, February) -> newest of each.
+	for _, want := range []string{"d0", "d2"} {
+		if !keep[want] {
+			t.Errorf("expected monthly bucket to keep %q", want)
+		}
+	}
+	// Weekly: 1 -> only the single newest week's backup.
+	if !keep["d0"] {
+		t.Errorf("expected weekly bucket to keep %q", "d0")
+	}
+
+	if keep["d4"] {
+		t.Errorf("d4 should not survive any bucket given these limits")
+	}
+}
+
+// TestGfsKeepWeeklyBucketCollision guards against a key collision bug
+// where the weekly bucket key was built with time.Time's "02" layout
+// verb (day-of-month), not an actual week number: for 2024, ISO week 2
+// and ISO week 33 both land on a day-of-month that formatted to the
+// same bogus key, so one of these two backups would have been silently
+// treated as a duplicate of the other and dropped.
+func TestGfsKeepWeeklyBucketCollision(t *testing.T) {
+	backups := []writer.ObjectInfo{
+		{Name: "w33", LastModified: mustParse(t, "2024-08-12")}, // ISO week 33
+		{Name: "w2", LastModified: mustParse(t, "2024-01-12")},  // ISO week 2
+	}
+
+	keep := gfsKeep(backups, GFSPolicy{Weekly: 2})
+
+	for _, want := range []string{"w33", "w2"} {
+		if !keep[want] {
+			t.Errorf("expected distinct ISO weeks to both be kept, missing %q: %v", want, keep)
+		}
+	}
+}
+
+// TestGfsKeepZeroPolicyKeepsNothing mirrors RetentionPolicy's documented
+// behavior at the gfsKeep level: a zero GFSPolicy keeps nothing, leaving
+// it to KeepLast/MaxTotalSizeBytes (or the caller's all-keep default) to
+// decide.
+func TestGfsKeepZeroPolicyKeepsNothing(t *testing.T) {
+	backups := []writer.ObjectInfo{
+		{Name: "d0", LastModified: mustParse(t, "2024-03-02")},
+	}
+	keep := gfsKeep(backups, GFSPolicy{})
+	if len(keep) != 0 {
+		t.Fatalf("expected no buckets kept, got %v", keep)
+	}
+}
+
+// TestApplyRetentionDeletesManifestAndCheckpointSidecars guards against
+// orphaned sidecars: List already filters manifests (and checkpoints)
+// out of enumeration, so a backup pruned out of the retention window
+// must take its manifest/checkpoint with it, or they'd never be seen by
+// a later sweep or ScrubStorage and would accumulate forever.
+func TestApplyRetentionDeletesManifestAndCheckpointSidecars(t *testing.T) {
+	d := &fakeDriver{
+		objects: []writer.ObjectInfo{
+			{Name: "job-old", LastModified: mustParse(t, "2024-01-01")},
+			{Name: "job-new", LastModified: mustParse(t, "2024-03-01")},
+		},
+	}
+
+	if err := ApplyRetention(context.Background(), d, "job", RetentionPolicy{KeepLast: 1}); err != nil {
+		t.Fatalf("ApplyRetention: %s", err)
+	}
+
+	for _, want := range []string{"job-old", "job-old" + integrity.ManifestSuffix, "job-old" + writer.CheckpointSuffix} {
+		if !d.deleted[want] {
+			t.Errorf("expected %q to be deleted, deleted = %v", want, d.deleted)
+		}
+	}
+	for _, unwanted := range []string{"job-new", "job-new" + integrity.ManifestSuffix, "job-new" + writer.CheckpointSuffix} {
+		if d.deleted[unwanted] {
+			t.Errorf("did not expect %q (kept backup) to be deleted", unwanted)
+		}
+	}
+}
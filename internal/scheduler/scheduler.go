@@ -0,0 +1,261 @@
+// Package scheduler runs backups on a cron schedule without requiring an
+// external trigger (cloud cron, systemd timer, ...) and prunes old
+// backups from storage according to a retention policy once each run
+// completes successfully.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/percona/percona-backup-mongodb/internal/storage"
+	"github.com/percona/percona-backup-mongodb/internal/writer"
+	pb "github.com/percona/percona-backup-mongodb/proto/messages"
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+)
+
+// BackupFunc produces the backup stream (typically piping mongodump)
+// into w. The scheduler wires w up to the configured storage/
+// compression/encryption writer chain; BackupFunc only needs to worry
+// about the plaintext bytes.
+type BackupFunc func(ctx context.Context, w io.Writer) error
+
+// Job is one scheduled, recurring backup.
+type Job struct {
+	Name     string
+	CronExpr string
+	Storage  storage.Storage
+	// Options selects compression/encryption for every run of this job,
+	// including pgzip's parallelism and block size when CompressionType is
+	// COMPRESSION_TYPE_GZIP. See writer.NewBackupWriter.
+	Options     *pb.BackupOptions
+	KeyProvider writer.KeyProvider
+	Retention   RetentionPolicy
+	Integrity   *writer.IntegrityOptions
+	Backup      BackupFunc
+}
+
+// Status is a point-in-time snapshot of a scheduled job, returned by
+// List and used to answer the coordinator's gRPC status queries.
+type Status struct {
+	Name    string
+	Paused  bool
+	Running bool
+	Next    time.Time
+	LastRun time.Time
+	LastErr error
+}
+
+// Event is emitted after every run, successful or not, so callers can
+// wire up metrics/alerting without polling Status.
+type Event struct {
+	Job     string
+	Started time.Time
+	Ended   time.Time
+	Err     error
+}
+
+// EventHandler receives one Event per completed run. It must not block
+// the scheduler for long; slow handlers should hand off asynchronously.
+type EventHandler func(Event)
+
+type jobEntry struct {
+	job     Job
+	entryID cron.EntryID
+
+	mu      sync.Mutex
+	paused  bool
+	running bool
+	lastRun time.Time
+	lastErr error
+}
+
+// Scheduler owns a set of cron-triggered backup Jobs.
+type Scheduler struct {
+	cron    *cron.Cron
+	onEvent EventHandler
+
+	mu   sync.RWMutex
+	jobs map[string]*jobEntry
+}
+
+// NewScheduler creates a Scheduler. onEvent may be nil.
+func NewScheduler(onEvent EventHandler) *Scheduler {
+	return &Scheduler{
+		cron:    cron.New(),
+		onEvent: onEvent,
+		jobs:    map[string]*jobEntry{},
+	}
+}
+
+// AddJob registers job and schedules it on its cron expression. The
+// scheduler must be started with Start for it to actually fire.
+func (s *Scheduler) AddJob(job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, dup := s.jobs[job.Name]; dup {
+		return fmt.Errorf("scheduler: job %q already registered", job.Name)
+	}
+	entry := &jobEntry{job: job}
+	id, err := s.cron.AddFunc(job.CronExpr, func() { s.run(job.Name) })
+	if err != nil {
+		return errors.Wrapf(err, "invalid cron expression %q for job %q", job.CronExpr, job.Name)
+	}
+	entry.entryID = id
+	s.jobs[job.Name] = entry
+	return nil
+}
+
+// Start begins firing scheduled jobs. It does not block.
+func (s *Scheduler) Start() {
+	s.cron.Start()
+}
+
+// Stop stops firing new runs and waits for any in-flight cron-triggered
+// run to finish. Runs started by TriggerNow are not waited for.
+func (s *Scheduler) Stop() {
+	<-s.cron.Stop().Done()
+}
+
+// Pause prevents job from firing until Resume is called. A run already
+// in progress is left to finish.
+func (s *Scheduler) Pause(name string) error {
+	entry, err := s.entry(name)
+	if err != nil {
+		return err
+	}
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	entry.paused = true
+	return nil
+}
+
+// Resume undoes Pause.
+func (s *Scheduler) Resume(name string) error {
+	entry, err := s.entry(name)
+	if err != nil {
+		return err
+	}
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	entry.paused = false
+	return nil
+}
+
+// TriggerNow runs job immediately, out of band from its cron schedule,
+// unless it is paused or already running.
+func (s *Scheduler) TriggerNow(name string) error {
+	entry, err := s.entry(name)
+	if err != nil {
+		return err
+	}
+	entry.mu.Lock()
+	paused := entry.paused
+	entry.mu.Unlock()
+	if paused {
+		return fmt.Errorf("scheduler: job %q is paused", name)
+	}
+	go s.run(name)
+	return nil
+}
+
+// List reports the current status of every registered job.
+func (s *Scheduler) List() []Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := s.cron.Entries()
+	next := map[cron.EntryID]time.Time{}
+	for _, e := range entries {
+		next[e.ID] = e.Next
+	}
+
+	statuses := make([]Status, 0, len(s.jobs))
+	for name, entry := range s.jobs {
+		entry.mu.Lock()
+		statuses = append(statuses, Status{
+			Name:    name,
+			Paused:  entry.paused,
+			Running: entry.running,
+			Next:    next[entry.entryID],
+			LastRun: entry.lastRun,
+			LastErr: entry.lastErr,
+		})
+		entry.mu.Unlock()
+	}
+	return statuses
+}
+
+func (s *Scheduler) entry(name string) (*jobEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entry, ok := s.jobs[name]
+	if !ok {
+		return nil, fmt.Errorf("scheduler: no such job %q", name)
+	}
+	return entry, nil
+}
+
+// run executes job's backup once, skipping it entirely if a previous
+// run is still in progress or the job is paused.
+func (s *Scheduler) run(name string) {
+	entry, err := s.entry(name)
+	if err != nil {
+		return
+	}
+
+	entry.mu.Lock()
+	if entry.paused || entry.running {
+		entry.mu.Unlock()
+		return
+	}
+	entry.running = true
+	entry.mu.Unlock()
+
+	started := time.Now()
+	err = s.runOnce(entry.job)
+	ended := time.Now()
+
+	entry.mu.Lock()
+	entry.running = false
+	entry.lastRun = started
+	entry.lastErr = err
+	entry.mu.Unlock()
+
+	if s.onEvent != nil {
+		s.onEvent(Event{Job: name, Started: started, Ended: ended, Err: err})
+	}
+}
+
+func (s *Scheduler) runOnce(job Job) error {
+	name := backupObjectName(job.Name, time.Now())
+	bw, err := writer.NewBackupWriter(name, job.Storage, job.Options, job.KeyProvider, job.Integrity)
+	if err != nil {
+		return errors.Wrap(err, "cannot open backup writer")
+	}
+
+	if err := job.Backup(context.Background(), bw); err != nil {
+		bw.Close()
+		return errors.Wrap(err, "backup function failed")
+	}
+	if err := bw.Close(); err != nil {
+		return errors.Wrap(err, "cannot finalize backup")
+	}
+
+	driver, err := writer.GetDriver(job.Storage)
+	if err != nil {
+		return errors.Wrap(err, "cannot open storage driver for retention sweep")
+	}
+	if err := ApplyRetention(context.Background(), driver, job.Name, job.Retention); err != nil {
+		return errors.Wrap(err, "retention sweep failed")
+	}
+	return nil
+}
+
+func backupObjectName(jobName string, when time.Time) string {
+	return fmt.Sprintf("%s-%s.bak", jobName, when.UTC().Format("20060102150405"))
+}
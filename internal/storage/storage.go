@@ -0,0 +1,58 @@
+// Package storage describes where a backup's bytes live: the storage
+// type selected in the agent config and the connection details needed
+// to reach it.
+package storage
+
+// Storage is the part of the agent config that tells a BackupWriter/
+// BackupReader where to read and write backup objects. Only the section
+// matching Type is consulted; the others may be left zero-valued.
+type Storage struct {
+	Type       string     `yaml:"type"`
+	Filesystem Filesystem `yaml:"filesystem,omitempty"`
+	S3         S3         `yaml:"s3,omitempty"`
+	GCS        GCS        `yaml:"gcs,omitempty"`
+	Azure      Azure      `yaml:"azure,omitempty"`
+}
+
+// Filesystem stores backups as plain files on a local or mounted path.
+type Filesystem struct {
+	Path string `yaml:"path"`
+}
+
+// S3 configures an Amazon S3 or S3-compatible (MinIO, Ceph, Wasabi, ...)
+// target. EndpointURL, ForcePathStyle and DisableSSL only matter for
+// non-AWS endpoints; they are no-ops against real S3.
+type S3 struct {
+	Region         string `yaml:"region"`
+	Bucket         string `yaml:"bucket"`
+	EndpointURL    string `yaml:"endpointUrl,omitempty"`
+	Credentials    Credentials
+	ForcePathStyle bool `yaml:"forcePathStyle,omitempty"`
+	DisableSSL     bool `yaml:"disableSSL,omitempty"`
+	// MultipartPartSize overrides the default part size (bytes) used by
+	// resumable multipart uploads. Zero means use the package default.
+	MultipartPartSize int64 `yaml:"multipartPartSize,omitempty"`
+}
+
+// Credentials holds static access keys. When empty, drivers fall back to
+// their SDK's default credential chain (env vars, instance profile, ...).
+type Credentials struct {
+	AccessKeyID     string `yaml:"accessKeyId,omitempty"`
+	SecretAccessKey string `yaml:"secretAccessKey,omitempty"`
+}
+
+// GCS configures a Google Cloud Storage target.
+type GCS struct {
+	Bucket          string `yaml:"bucket"`
+	CredentialsFile string `yaml:"credentialsFile,omitempty"`
+	ProjectID       string `yaml:"projectId,omitempty"`
+}
+
+// Azure configures an Azure Blob Storage target. Either SASToken or
+// AccountKey should be set; SASToken takes precedence when both are.
+type Azure struct {
+	Account    string `yaml:"account"`
+	Container  string `yaml:"container"`
+	AccountKey string `yaml:"accountKey,omitempty"`
+	SASToken   string `yaml:"sasToken,omitempty"`
+}
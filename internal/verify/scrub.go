@@ -0,0 +1,36 @@
+package verify
+
+import (
+	"context"
+
+	"github.com/percona/percona-backup-mongodb/internal/storage"
+	"github.com/percona/percona-backup-mongodb/internal/writer"
+	pb "github.com/percona/percona-backup-mongodb/proto/messages"
+	"github.com/pkg/errors"
+)
+
+// ScrubStorage verifies every backup object in stg (driver.List already
+// excludes manifests and multipart checkpoints, so every name it
+// returns is a backup to check) against its manifest, for a periodic
+// integrity sweep independent of any particular restore.
+func ScrubStorage(stg storage.Storage, compressionType pb.CompressionType, cypher pb.Cypher,
+	keyProvider writer.KeyProvider, manifestKey []byte) ([]*Result, error) {
+	driver, err := writer.GetDriver(stg)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := driver.List(context.Background())
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot list storage target")
+	}
+
+	results := make([]*Result, 0, len(objects))
+	for _, obj := range objects {
+		res, err := VerifyBackup(obj.Name, stg, compressionType, cypher, keyProvider, manifestKey)
+		if err != nil {
+			res = &Result{Name: obj.Name, Errors: []string{err.Error()}}
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
@@ -0,0 +1,166 @@
+// Package verify checks a backup written by internal/writer against the
+// manifest it produced: re-hashing the stored ciphertext and the
+// decoded plaintext and comparing them (and the plaintext's Merkle
+// root) against what the manifest recorded.
+package verify
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+
+	"github.com/percona/percona-backup-mongodb/internal/integrity"
+	"github.com/percona/percona-backup-mongodb/internal/reader"
+	"github.com/percona/percona-backup-mongodb/internal/storage"
+	"github.com/percona/percona-backup-mongodb/internal/writer"
+	pb "github.com/percona/percona-backup-mongodb/proto/messages"
+	"github.com/pkg/errors"
+)
+
+// Result reports which of a backup's integrity checks passed. A backup
+// is only sound if OK() is true; the individual fields let a scrub
+// report point at exactly what's wrong.
+type Result struct {
+	Name              string
+	ManifestSignature bool
+	CiphertextDigest  bool
+	PlaintextDigest   bool
+	PlaintextMerkle   bool
+	SizeMatch         bool
+	Errors            []string
+}
+
+// OK reports whether every check that ran passed and nothing errored
+// out before it could run.
+func (r *Result) OK() bool {
+	return len(r.Errors) == 0 && r.ManifestSignature && r.CiphertextDigest &&
+		r.PlaintextDigest && r.PlaintextMerkle && r.SizeMatch
+}
+
+// VerifyBackup streams name back from stg (once as raw ciphertext, once
+// decoded through compression/decryption) and checks both against the
+// manifest written by BackupWriter. manifestKey must match whatever
+// IntegrityOptions.ManifestKey the backup was written with; pass nil if
+// it wasn't signed.
+func VerifyBackup(name string, stg storage.Storage, compressionType pb.CompressionType,
+	cypher pb.Cypher, keyProvider writer.KeyProvider, manifestKey []byte) (*Result, error) {
+	res := &Result{Name: name}
+	ctx := context.Background()
+
+	driver, err := writer.GetDriver(stg)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := readManifest(ctx, driver, name)
+	if err != nil {
+		res.Errors = append(res.Errors, err.Error())
+		return res, nil
+	}
+
+	if len(manifestKey) > 0 {
+		ok, err := manifest.VerifySignature(manifestKey)
+		if err != nil {
+			res.Errors = append(res.Errors, errors.Wrap(err, "cannot verify manifest signature").Error())
+		} else {
+			res.ManifestSignature = ok
+		}
+	} else {
+		res.ManifestSignature = true
+	}
+
+	cipherOK, cipherSizeOK, err := verifyCiphertext(ctx, driver, name, manifest)
+	if err != nil {
+		res.Errors = append(res.Errors, err.Error())
+	}
+	res.CiphertextDigest = cipherOK
+
+	plainOK, merkleOK, plainSizeOK, err := verifyPlaintext(name, stg, compressionType, cypher, keyProvider, manifest)
+	if err != nil {
+		res.Errors = append(res.Errors, err.Error())
+	}
+	res.PlaintextDigest = plainOK
+	res.PlaintextMerkle = merkleOK
+	res.SizeMatch = cipherSizeOK && plainSizeOK
+
+	return res, nil
+}
+
+func readManifest(ctx context.Context, driver writer.StorageDriver, name string) (*integrity.Manifest, error) {
+	mr, err := driver.NewReader(ctx, name+integrity.ManifestSuffix)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open manifest for %q (was it written with integrity checking on?)", name)
+	}
+	defer mr.Close()
+	body, err := ioutil.ReadAll(mr)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read manifest")
+	}
+	return integrity.UnmarshalManifest(body)
+}
+
+func verifyCiphertext(ctx context.Context, driver writer.StorageDriver, name string, manifest *integrity.Manifest) (digestOK, sizeOK bool, err error) {
+	rc, err := driver.NewReader(ctx, name)
+	if err != nil {
+		return false, false, errors.Wrapf(err, "cannot open %q for reading", name)
+	}
+	defer rc.Close()
+
+	h, err := integrity.NewHash(manifest.Algorithm)
+	if err != nil {
+		return false, false, err
+	}
+	n, err := io.Copy(h, rc)
+	if err != nil {
+		return false, false, errors.Wrap(err, "cannot read backup object")
+	}
+	return bytes.Equal(h.Sum(nil), manifest.CiphertextDigest), n == manifest.CompressedSize, nil
+}
+
+func verifyPlaintext(name string, stg storage.Storage, compressionType pb.CompressionType, cypher pb.Cypher,
+	keyProvider writer.KeyProvider, manifest *integrity.Manifest) (digestOK, merkleOK, sizeOK bool, err error) {
+	br, err := reader.NewBackupReader(name, stg, compressionType, cypher, keyProvider)
+	if err != nil {
+		return false, false, false, errors.Wrap(err, "cannot open backup for plaintext verification")
+	}
+	defer br.Close()
+
+	h, err := integrity.NewHash(manifest.Algorithm)
+	if err != nil {
+		return false, false, false, err
+	}
+
+	var leaves [][]byte
+	var total int64
+	buf := make([]byte, integrity.ChunkSize)
+	for {
+		n, rerr := io.ReadFull(br, buf)
+		if n > 0 {
+			h.Write(buf[:n])
+			total += int64(n)
+			leafHash, lerr := integrity.NewHash(manifest.Algorithm)
+			if lerr != nil {
+				return false, false, false, lerr
+			}
+			leafHash.Write(buf[:n])
+			leaves = append(leaves, leafHash.Sum(nil))
+		}
+		if rerr == io.EOF || rerr == io.ErrUnexpectedEOF {
+			break
+		}
+		if rerr != nil {
+			return false, false, false, errors.Wrap(rerr, "cannot read decoded backup stream")
+		}
+	}
+
+	root, err := integrity.MerkleRoot(manifest.Algorithm, leaves)
+	if err != nil {
+		return false, false, false, err
+	}
+
+	return bytes.Equal(h.Sum(nil), manifest.PlaintextDigest),
+		bytes.Equal(root, manifest.PlaintextMerkle),
+		total == manifest.OriginalSize,
+		nil
+}
@@ -0,0 +1,431 @@
+package writer
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+
+	pb "github.com/percona/percona-backup-mongodb/proto/messages"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/scrypt"
+)
+
+// cypherMagic identifies a PBM-encrypted stream on disk/in object storage.
+var cypherMagic = [4]byte{'P', 'B', 'M', 'E'}
+
+const cypherVersion = 1
+
+// chunkSize is the size of the plaintext chunk encrypted under its own
+// AES-GCM nonce. Keeping it fixed lets the reader seek/verify chunk by
+// chunk instead of buffering the whole backup in memory.
+const chunkSize = 64 * 1024
+
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+	saltSize     = 16
+	nonceSize    = 12
+)
+
+// KeyProvider wraps and unwraps the per-backup data encryption key (DEK).
+// A passphrase-based provider derives the wrapping key locally; an
+// envelope provider (AWS KMS, GCP, Vault, ...) calls out to an external
+// service. Implementations must be safe to reuse across backups.
+type KeyProvider interface {
+	// WrapKey encrypts dek and returns the bytes to store in the stream header.
+	WrapKey(dek []byte) ([]byte, error)
+	// UnwrapKey reverses WrapKey.
+	UnwrapKey(wrapped []byte) ([]byte, error)
+}
+
+// CypherOptions configures NewCypherWriter/NewCypherReader.
+type CypherOptions struct {
+	Cypher      pb.Cypher
+	KeyProvider KeyProvider
+}
+
+// passphraseKeyProvider derives the wrapping key from a user passphrase
+// with scrypt. The salt is random per backup and travels in the header,
+// so UnwrapKey needs it passed in out of band (newAESGCMReader sets it
+// from the header before unwrapping).
+type passphraseKeyProvider struct {
+	passphrase []byte
+	salt       []byte
+}
+
+// NewPassphraseKeyProvider returns a KeyProvider that wraps the DEK with a
+// key derived from passphrase via scrypt. salt should be nil when wrapping
+// (a random salt is generated and made available via Salt()) and the salt
+// read back from the stream header when unwrapping.
+func NewPassphraseKeyProvider(passphrase []byte, salt []byte) (*passphraseKeyProvider, error) {
+	if len(salt) == 0 {
+		salt = make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, errors.Wrap(err, "cannot generate scrypt salt")
+		}
+	}
+	return &passphraseKeyProvider{passphrase: passphrase, salt: salt}, nil
+}
+
+// Salt returns the scrypt salt in use so callers can persist it in the header.
+func (p *passphraseKeyProvider) Salt() []byte {
+	return p.salt
+}
+
+func (p *passphraseKeyProvider) wrappingKey() ([]byte, error) {
+	return scrypt.Key(p.passphrase, p.salt, scryptN, scryptR, scryptP, scryptKeyLen)
+}
+
+func (p *passphraseKeyProvider) WrapKey(dek []byte) ([]byte, error) {
+	key, err := p.wrappingKey()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot derive wrapping key")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create AES cipher")
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create GCM")
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "cannot generate key-wrap nonce")
+	}
+	return gcm.Seal(nonce, nonce, dek, nil), nil
+}
+
+func (p *passphraseKeyProvider) UnwrapKey(wrapped []byte) ([]byte, error) {
+	key, err := p.wrappingKey()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot derive wrapping key")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create AES cipher")
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create GCM")
+	}
+	if len(wrapped) < gcm.NonceSize() {
+		return nil, errors.New("wrapped key is too short")
+	}
+	nonce, ciphertext := wrapped[:gcm.NonceSize()], wrapped[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+// cypherHeader is serialized at the start of an encrypted stream so a
+// reader can reconstruct the DEK and chunking parameters without any
+// other side channel.
+type cypherHeader struct {
+	cypher     pb.Cypher
+	fileNonce  [nonceSize - 8]byte // leading bytes of the per-chunk nonce; the last 8 are the counter
+	salt       []byte
+	wrappedDEK []byte
+	chunkSize  uint32
+}
+
+func (h *cypherHeader) marshal() []byte {
+	buf := make([]byte, 0, 4+1+1+4+2+len(h.salt)+2+len(h.wrappedDEK)+len(h.fileNonce))
+	buf = append(buf, cypherMagic[:]...)
+	buf = append(buf, cypherVersion)
+	buf = append(buf, byte(h.cypher))
+	sizeBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(sizeBuf, h.chunkSize)
+	buf = append(buf, sizeBuf...)
+	buf = append(buf, h.fileNonce[:]...)
+	buf = appendUint16Prefixed(buf, h.salt)
+	buf = appendUint16Prefixed(buf, h.wrappedDEK)
+	return buf
+}
+
+func appendUint16Prefixed(buf, data []byte) []byte {
+	lenBuf := make([]byte, 2)
+	binary.BigEndian.PutUint16(lenBuf, uint16(len(data)))
+	buf = append(buf, lenBuf...)
+	return append(buf, data...)
+}
+
+func readUint16Prefixed(r io.Reader) ([]byte, error) {
+	lenBuf := make([]byte, 2)
+	if _, err := io.ReadFull(r, lenBuf); err != nil {
+		return nil, err
+	}
+	data := make([]byte, binary.BigEndian.Uint16(lenBuf))
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// cypherWriter is an io.WriteCloser that buffers plaintext into chunkSize
+// chunks and emits each as an independently authenticated AES-256-GCM
+// record: len-prefixed ciphertext+tag. The per-chunk nonce is fileNonce
+// concatenated with a big-endian chunk counter, so nonces never repeat
+// for the lifetime of the DEK.
+type cypherWriter struct {
+	dst     io.WriteCloser
+	gcm     cipher.AEAD
+	nonce   [nonceSize]byte
+	counter uint64
+	buf     []byte
+}
+
+// NewCypherWriter wraps dst so that everything written to the returned
+// io.WriteCloser is encrypted before reaching dst. The header (salt,
+// wrapped DEK, chunk size, file nonce prefix) is written immediately.
+func NewCypherWriter(dst io.WriteCloser, opts CypherOptions) (io.WriteCloser, error) {
+	switch opts.Cypher {
+	case pb.Cypher_CYPHER_NO_CYPHER:
+		return dst, nil
+	case pb.Cypher_CYPHER_AES256_GCM:
+		return newAESGCMWriter(dst, opts.KeyProvider)
+	default:
+		return nil, errors.Errorf("unsupported cypher %v", opts.Cypher)
+	}
+}
+
+func newAESGCMWriter(dst io.WriteCloser, kp KeyProvider) (*cypherWriter, error) {
+	if kp == nil {
+		return nil, errors.New("AES-256-GCM requires a KeyProvider")
+	}
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, errors.Wrap(err, "cannot generate DEK")
+	}
+	wrapped, err := kp.WrapKey(dek)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot wrap DEK")
+	}
+	block, err := aes.NewCipher(dek)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create AES cipher")
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create GCM")
+	}
+
+	cw := &cypherWriter{dst: dst, gcm: gcm, buf: make([]byte, 0, chunkSize)}
+	if _, err := rand.Read(cw.nonce[:len(cw.nonce)-8]); err != nil {
+		return nil, errors.Wrap(err, "cannot generate file nonce")
+	}
+
+	header := &cypherHeader{
+		cypher:     pb.Cypher_CYPHER_AES256_GCM,
+		wrappedDEK: wrapped,
+		chunkSize:  chunkSize,
+	}
+	copy(header.fileNonce[:], cw.nonce[:len(cw.nonce)-8])
+	if ppk, ok := kp.(*passphraseKeyProvider); ok {
+		header.salt = ppk.Salt()
+	}
+	if _, err := dst.Write(header.marshal()); err != nil {
+		return nil, errors.Wrap(err, "cannot write cypher header")
+	}
+	return cw, nil
+}
+
+func (cw *cypherWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := copy(cw.buf[len(cw.buf):cap(cw.buf)], p)
+		cw.buf = cw.buf[:len(cw.buf)+n]
+		p = p[n:]
+		written += n
+		if len(cw.buf) == cap(cw.buf) {
+			if err := cw.flushChunk(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Flush lets BackupWriter.Close drain a partial final chunk before closing
+// the underlying destination writer.
+func (cw *cypherWriter) Flush() error {
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	return cw.flushChunk()
+}
+
+func (cw *cypherWriter) flushChunk() error {
+	binary.BigEndian.PutUint64(cw.nonce[len(cw.nonce)-8:], cw.counter)
+	sealed := cw.gcm.Seal(nil, cw.nonce[:], cw.buf, nil)
+	cw.buf = cw.buf[:0]
+	cw.counter++
+
+	lenBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(lenBuf, uint32(len(sealed)))
+	if _, err := cw.dst.Write(lenBuf); err != nil {
+		return errors.Wrap(err, "cannot write chunk length")
+	}
+	if _, err := cw.dst.Write(sealed); err != nil {
+		return errors.Wrap(err, "cannot write encrypted chunk")
+	}
+	return nil
+}
+
+// Close only flushes the final partial chunk; it deliberately does not
+// close cw.dst. cw is one link in BackupWriter's writer chain, and
+// BackupWriter.Close already closes every link itself, one by one —
+// closing dst here too would double-close whatever comes next in the
+// chain (e.g. the storage driver's *os.File), exactly like gzip/lz4/
+// snappy's own Close() never touches the writer they wrap.
+func (cw *cypherWriter) Close() error {
+	return cw.Flush()
+}
+
+// cypherReader is the decrypting counterpart of cypherWriter. It reads
+// and verifies one chunk at a time, refusing to return plaintext for a
+// chunk whose auth tag doesn't check out or whose counter is out of
+// sequence (which also catches reordering, since the nonce counter is
+// strictly increasing). A stream cut off inside a chunk is an error; a
+// stream that ends cleanly at a chunk boundary reads as io.EOF, so
+// whole trailing chunks that are missing are not detected.
+type cypherReader struct {
+	src      io.ReadCloser
+	gcm      cipher.AEAD
+	nonce    [nonceSize]byte
+	counter  uint64
+	plain    []byte
+	plainPos int
+	err      error
+}
+
+// NewCypherReader reads the header written by NewCypherWriter from src,
+// unwraps the DEK via opts.KeyProvider and returns a reader that yields
+// the original plaintext.
+func NewCypherReader(src io.ReadCloser, opts CypherOptions) (io.ReadCloser, error) {
+	switch opts.Cypher {
+	case pb.Cypher_CYPHER_NO_CYPHER:
+		return src, nil
+	case pb.Cypher_CYPHER_AES256_GCM:
+		return newAESGCMReader(src, opts.KeyProvider)
+	default:
+		return nil, errors.Errorf("unsupported cypher %v", opts.Cypher)
+	}
+}
+
+func newAESGCMReader(src io.ReadCloser, kp KeyProvider) (*cypherReader, error) {
+	if kp == nil {
+		return nil, errors.New("AES-256-GCM requires a KeyProvider")
+	}
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(src, magic); err != nil {
+		return nil, errors.Wrap(err, "cannot read cypher magic")
+	}
+	if string(magic) != string(cypherMagic[:]) {
+		return nil, errors.New("not a PBM encrypted stream")
+	}
+	verBuf := make([]byte, 1)
+	if _, err := io.ReadFull(src, verBuf); err != nil {
+		return nil, errors.Wrap(err, "cannot read cypher version")
+	}
+	if verBuf[0] != cypherVersion {
+		return nil, errors.Errorf("unsupported cypher stream version %d", verBuf[0])
+	}
+	cypherIDBuf := make([]byte, 1)
+	if _, err := io.ReadFull(src, cypherIDBuf); err != nil {
+		return nil, errors.Wrap(err, "cannot read cypher id")
+	}
+	if pb.Cypher(cypherIDBuf[0]) != pb.Cypher_CYPHER_AES256_GCM {
+		return nil, errors.New("cypher id in stream does not match AES-256-GCM")
+	}
+	sizeBuf := make([]byte, 4)
+	if _, err := io.ReadFull(src, sizeBuf); err != nil {
+		return nil, errors.Wrap(err, "cannot read chunk size")
+	}
+
+	cr := &cypherReader{src: src}
+	fileNonce := make([]byte, nonceSize-8)
+	if _, err := io.ReadFull(src, fileNonce); err != nil {
+		return nil, errors.Wrap(err, "cannot read file nonce")
+	}
+	copy(cr.nonce[:len(cr.nonce)-8], fileNonce)
+
+	salt, err := readUint16Prefixed(src)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read salt")
+	}
+	wrappedDEK, err := readUint16Prefixed(src)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read wrapped DEK")
+	}
+
+	if ppk, ok := kp.(*passphraseKeyProvider); ok && len(salt) > 0 {
+		ppk.salt = salt
+	}
+	dek, err := kp.UnwrapKey(wrappedDEK)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot unwrap DEK")
+	}
+	block, err := aes.NewCipher(dek)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create AES cipher")
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create GCM")
+	}
+	cr.gcm = gcm
+	return cr, nil
+}
+
+func (cr *cypherReader) Read(p []byte) (int, error) {
+	if cr.plainPos >= len(cr.plain) {
+		if cr.err != nil {
+			return 0, cr.err
+		}
+		if err := cr.readChunk(); err != nil {
+			cr.err = err
+			if len(cr.plain) == 0 {
+				return 0, err
+			}
+		}
+	}
+	n := copy(p, cr.plain[cr.plainPos:])
+	cr.plainPos += n
+	return n, nil
+}
+
+func (cr *cypherReader) readChunk() error {
+	lenBuf := make([]byte, 4)
+	if _, err := io.ReadFull(cr.src, lenBuf); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return errors.New("encrypted stream ends with a truncated chunk")
+		}
+		return err
+	}
+	chunkLen := binary.BigEndian.Uint32(lenBuf)
+	sealed := make([]byte, chunkLen)
+	if _, err := io.ReadFull(cr.src, sealed); err != nil {
+		return errors.Wrap(err, "encrypted stream ends with a truncated chunk")
+	}
+
+	binary.BigEndian.PutUint64(cr.nonce[len(cr.nonce)-8:], cr.counter)
+	plain, err := cr.gcm.Open(nil, cr.nonce[:], sealed, nil)
+	if err != nil {
+		return errors.Wrapf(err, "chunk %d failed authentication (truncated, reordered or tampered stream)", cr.counter)
+	}
+	cr.counter++
+	cr.plain = plain
+	cr.plainPos = 0
+	return nil
+}
+
+// Close is a no-op: cr holds no resource of its own beyond cr.src, and
+// BackupReader.Close already closes every link in the reader chain
+// itself, so closing cr.src here would double-close it.
+func (cr *cypherReader) Close() error {
+	return nil
+}
@@ -0,0 +1,74 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/percona/percona-backup-mongodb/internal/storage"
+)
+
+// ObjectInfo describes one object a StorageDriver knows about, enough
+// for a retention policy to decide whether to keep or delete it.
+type ObjectInfo struct {
+	Name         string
+	Size         int64
+	LastModified time.Time
+}
+
+// StorageDriver abstracts the object-storage backend a backup is written
+// to and read from. It is deliberately symmetric with BackupWriter/
+// BackupReader: callers never see the backend-specific SDK types, only
+// plain io.WriteCloser/io.ReadCloser.
+type StorageDriver interface {
+	// NewWriter opens name for writing. The returned writer's Close must
+	// block until the object is durably stored (or return an error if it
+	// isn't).
+	NewWriter(ctx context.Context, name string) (io.WriteCloser, error)
+	// NewReader opens name for reading.
+	NewReader(ctx context.Context, name string) (io.ReadCloser, error)
+	// List enumerates every object in the target, for retention sweeps
+	// and scrubbing; it is not used on the hot backup/restore path.
+	List(ctx context.Context) ([]ObjectInfo, error)
+	// Delete removes name. It must not error when name doesn't exist.
+	Delete(ctx context.Context, name string) error
+}
+
+// DriverFactory builds a StorageDriver from the storage section of the
+// agent config. Drivers register one via RegisterDriver in an init func.
+type DriverFactory func(stg storage.Storage) (StorageDriver, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]DriverFactory{}
+)
+
+// RegisterDriver makes a storage driver available under name (matched
+// case-insensitively against storage.Storage.Type). Calling RegisterDriver
+// twice with the same name panics, mirroring database/sql's driver registry.
+func RegisterDriver(name string, factory DriverFactory) {
+	name = strings.ToLower(name)
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if _, dup := drivers[name]; dup {
+		panic("writer: RegisterDriver called twice for driver " + name)
+	}
+	drivers[name] = factory
+}
+
+// GetDriver instantiates the StorageDriver configured by stg.Type. It is
+// exported so internal/reader can open the same kind of storage backend
+// that internal/writer wrote to.
+func GetDriver(stg storage.Storage) (StorageDriver, error) {
+	name := strings.ToLower(stg.Type)
+	driversMu.RLock()
+	factory, ok := drivers[name]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("don't know how to handle %q storage type", stg.Type)
+	}
+	return factory(stg)
+}
@@ -0,0 +1,124 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/percona/percona-backup-mongodb/internal/integrity"
+	"github.com/percona/percona-backup-mongodb/internal/storage"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	RegisterDriver("azure", newAzureDriver)
+}
+
+func newAzureDriver(stg storage.Storage) (StorageDriver, error) {
+	var credential azblob.Credential
+	var err error
+	switch {
+	case stg.Azure.SASToken != "":
+		credential = azblob.NewAnonymousCredential()
+	case stg.Azure.AccountKey != "":
+		credential, err = azblob.NewSharedKeyCredential(stg.Azure.Account, stg.Azure.AccountKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot create Azure shared key credential")
+		}
+	default:
+		return nil, errors.New("azure storage requires either accountKey or sasToken")
+	}
+
+	rawURL := fmt.Sprintf("https://%s.blob.core.windows.net/%s", stg.Azure.Account, stg.Azure.Container)
+	if stg.Azure.SASToken != "" {
+		rawURL += "?" + stg.Azure.SASToken
+	}
+	containerURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid Azure container URL")
+	}
+
+	pipeline := azblob.NewPipeline(credential, azblob.PipelineOptions{})
+	return &azureDriver{container: azblob.NewContainerURL(*containerURL, pipeline)}, nil
+}
+
+type azureDriver struct {
+	container azblob.ContainerURL
+}
+
+func (d *azureDriver) NewWriter(ctx context.Context, name string) (io.WriteCloser, error) {
+	blobURL := d.container.NewBlockBlobURL(name)
+	pr, pw := io.Pipe()
+	aw := &azureWriter{pipeWriter: pw, done: make(chan struct{})}
+	go func() {
+		defer close(aw.done)
+		_, aw.uploadErr = azblob.UploadStreamToBlockBlob(ctx, pr, blobURL, azblob.UploadStreamToBlockBlobOptions{})
+	}()
+	return aw, nil
+}
+
+func (d *azureDriver) NewReader(ctx context.Context, name string) (io.ReadCloser, error) {
+	blobURL := d.container.NewBlockBlobURL(name)
+	resp, err := blobURL.Download(ctx, 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open Azure blob %q", name)
+	}
+	return resp.Body(azblob.RetryReaderOptions{}), nil
+}
+
+func (d *azureDriver) List(ctx context.Context) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		resp, err := d.container.ListBlobsFlatSegment(ctx, marker, azblob.ListBlobsSegmentOptions{})
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot list Azure container")
+		}
+		for _, blob := range resp.Segment.BlobItems {
+			if strings.HasSuffix(blob.Name, integrity.ManifestSuffix) {
+				continue
+			}
+			size := int64(0)
+			if blob.Properties.ContentLength != nil {
+				size = *blob.Properties.ContentLength
+			}
+			objects = append(objects, ObjectInfo{
+				Name:         blob.Name,
+				Size:         size,
+				LastModified: blob.Properties.LastModified,
+			})
+		}
+		marker = resp.NextMarker
+	}
+	return objects, nil
+}
+
+func (d *azureDriver) Delete(ctx context.Context, name string) error {
+	blobURL := d.container.NewBlockBlobURL(name)
+	_, err := blobURL.Delete(ctx, azblob.DeleteSnapshotsOptionNone, azblob.BlobAccessConditions{})
+	if err != nil {
+		if serr, ok := err.(azblob.StorageError); ok && serr.ServiceCode() == azblob.ServiceCodeBlobNotFound {
+			return nil
+		}
+		return errors.Wrapf(err, "cannot delete Azure blob %q", name)
+	}
+	return nil
+}
+
+type azureWriter struct {
+	pipeWriter *io.PipeWriter
+	done       chan struct{}
+	uploadErr  error
+}
+
+func (w *azureWriter) Write(p []byte) (int, error) {
+	return w.pipeWriter.Write(p)
+}
+
+func (w *azureWriter) Close() error {
+	w.pipeWriter.Close()
+	<-w.done
+	return w.uploadErr
+}
@@ -0,0 +1,65 @@
+package writer
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/percona/percona-backup-mongodb/internal/integrity"
+	"github.com/percona/percona-backup-mongodb/internal/storage"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	RegisterDriver("filesystem", func(stg storage.Storage) (StorageDriver, error) {
+		return &filesystemDriver{root: stg.Filesystem.Path}, nil
+	})
+}
+
+type filesystemDriver struct {
+	root string
+}
+
+func (d *filesystemDriver) NewWriter(_ context.Context, name string) (io.WriteCloser, error) {
+	filepath := path.Join(d.root, name)
+	fw, err := os.Create(filepath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot create destination file: %s", filepath)
+	}
+	return fw, nil
+}
+
+func (d *filesystemDriver) NewReader(_ context.Context, name string) (io.ReadCloser, error) {
+	filepath := path.Join(d.root, name)
+	fr, err := os.Open(filepath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open backup file: %s", filepath)
+	}
+	return fr, nil
+}
+
+func (d *filesystemDriver) List(_ context.Context) ([]ObjectInfo, error) {
+	entries, err := ioutil.ReadDir(d.root)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot list %s", d.root)
+	}
+	objects := make([]ObjectInfo, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || strings.HasSuffix(e.Name(), integrity.ManifestSuffix) {
+			continue
+		}
+		objects = append(objects, ObjectInfo{Name: e.Name(), Size: e.Size(), LastModified: e.ModTime()})
+	}
+	return objects, nil
+}
+
+func (d *filesystemDriver) Delete(_ context.Context, name string) error {
+	filepath := path.Join(d.root, name)
+	if err := os.Remove(filepath); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "cannot delete %s", filepath)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package writer
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+	gstorage "google.golang.org/api/option"
+
+	"github.com/percona/percona-backup-mongodb/internal/integrity"
+	pbmstorage "github.com/percona/percona-backup-mongodb/internal/storage"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	RegisterDriver("gcs", newGCSDriver)
+}
+
+func newGCSDriver(stg pbmstorage.Storage) (StorageDriver, error) {
+	ctx := context.Background()
+	var opts []gstorage.ClientOption
+	if stg.GCS.CredentialsFile != "" {
+		opts = append(opts, gstorage.WithCredentialsFile(stg.GCS.CredentialsFile))
+	}
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create GCS client")
+	}
+	return &gcsDriver{client: client, bucket: stg.GCS.Bucket}, nil
+}
+
+type gcsDriver struct {
+	client *storage.Client
+	bucket string
+}
+
+func (d *gcsDriver) NewWriter(ctx context.Context, name string) (io.WriteCloser, error) {
+	return d.client.Bucket(d.bucket).Object(name).NewWriter(ctx), nil
+}
+
+func (d *gcsDriver) NewReader(ctx context.Context, name string) (io.ReadCloser, error) {
+	r, err := d.client.Bucket(d.bucket).Object(name).NewReader(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open GCS object %q", name)
+	}
+	return r, nil
+}
+
+func (d *gcsDriver) List(ctx context.Context) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	it := d.client.Bucket(d.bucket).Objects(ctx, nil)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot list GCS bucket %q", d.bucket)
+		}
+		if strings.HasSuffix(attrs.Name, integrity.ManifestSuffix) {
+			continue
+		}
+		objects = append(objects, ObjectInfo{Name: attrs.Name, Size: attrs.Size, LastModified: attrs.Updated})
+	}
+	return objects, nil
+}
+
+func (d *gcsDriver) Delete(ctx context.Context, name string) error {
+	if err := d.client.Bucket(d.bucket).Object(name).Delete(ctx); err != nil && err != storage.ErrObjectNotExist {
+		return errors.Wrapf(err, "cannot delete GCS object %q", name)
+	}
+	return nil
+}
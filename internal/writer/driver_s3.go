@@ -0,0 +1,94 @@
+package writer
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/percona/percona-backup-mongodb/internal/awsutils"
+	"github.com/percona/percona-backup-mongodb/internal/integrity"
+	"github.com/percona/percona-backup-mongodb/internal/storage"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	RegisterDriver("s3", newS3Driver)
+	RegisterDriver("s3compatible", newS3Driver)
+}
+
+// newS3Driver also backs the "s3compatible" alias: the only difference
+// between Amazon S3 and MinIO/Ceph/Wasabi is the session configuration
+// (custom endpoint, path-style addressing, disabled TLS), which
+// awsutils.GetAWSSessionFromStorage already derives from stg.S3.
+func newS3Driver(stg storage.Storage) (StorageDriver, error) {
+	sess, err := awsutils.GetAWSSessionFromStorage(stg.S3)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get an AWS session")
+	}
+	return &s3Driver{session: sess, bucket: stg.S3.Bucket, partSize: stg.S3.MultipartPartSize}, nil
+}
+
+type s3Driver struct {
+	session  *session.Session
+	bucket   string
+	partSize int64
+}
+
+// NewWriter uploads name as a multipart upload, checkpointing after
+// every completed part (see driver_s3_multipart.go). A transient
+// network failure only fails the part being retried, rather than
+// discarding the whole in-flight backup the way the old s3manager.
+// Uploader-over-an-io.Pipe approach did.
+func (d *s3Driver) NewWriter(ctx context.Context, name string) (io.WriteCloser, error) {
+	return newS3MultipartWriter(s3.New(d.session), d.bucket, name, d.partSize, nil)
+}
+
+func (d *s3Driver) NewReader(ctx context.Context, name string) (io.ReadCloser, error) {
+	out, err := s3.New(d.session).GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(d.bucket),
+		Key:    aws.String(name),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot get object %q", name)
+	}
+	return out.Body, nil
+}
+
+func (d *s3Driver) List(ctx context.Context) ([]ObjectInfo, error) {
+	client := s3.New(d.session)
+	var objects []ObjectInfo
+	err := client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(d.bucket),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			key := aws.StringValue(obj.Key)
+			if strings.HasSuffix(key, CheckpointSuffix) || strings.HasSuffix(key, integrity.ManifestSuffix) {
+				continue
+			}
+			objects = append(objects, ObjectInfo{
+				Name:         key,
+				Size:         aws.Int64Value(obj.Size),
+				LastModified: aws.TimeValue(obj.LastModified),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot list bucket %q", d.bucket)
+	}
+	return objects, nil
+}
+
+func (d *s3Driver) Delete(ctx context.Context, name string) error {
+	_, err := s3.New(d.session).DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(d.bucket),
+		Key:    aws.String(name),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "cannot delete object %q", name)
+	}
+	return nil
+}
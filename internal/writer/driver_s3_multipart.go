@@ -0,0 +1,396 @@
+package writer
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/percona/percona-backup-mongodb/internal/storage"
+	pb "github.com/percona/percona-backup-mongodb/proto/messages"
+	"github.com/pkg/errors"
+)
+
+const (
+	// minPartSize is S3's own minimum for any part but the last one.
+	minPartSize = 5 * 1024 * 1024
+	// defaultPartSize is used when storage.S3.MultipartPartSize is unset.
+	defaultPartSize = 16 * 1024 * 1024
+	// maxInFlightParts bounds how many part uploads can be buffered and
+	// in flight at once, capping memory use to roughly partSize*this.
+	maxInFlightParts = 4
+	// maxPartRetries is the number of attempts per part before giving up.
+	maxPartRetries = 5
+
+	// CheckpointSuffix is appended to a backup's object name to get the
+	// name its multipart resume checkpoint is stored under.
+	CheckpointSuffix = ".pbm.checkpoint.json"
+)
+
+// completedPart mirrors s3.CompletedPart plus the part's size in bytes,
+// which CompleteMultipartUpload doesn't need but the checkpoint's
+// Offset does.
+type completedPart struct {
+	Number int64  `json:"number"`
+	ETag   string `json:"etag"`
+	Size   int64  `json:"size"`
+}
+
+// multipartCheckpoint is persisted to <name>.pbm.checkpoint.json after
+// every completed part so a failed agent can resume the upload instead
+// of restarting it from scratch.
+type multipartCheckpoint struct {
+	UploadID string          `json:"uploadId"`
+	Bucket   string          `json:"bucket"`
+	Key      string          `json:"key"`
+	PartSize int64           `json:"partSize"`
+	Parts    []completedPart `json:"parts"`
+}
+
+// ResumeBackupWriter continues an S3 multipart upload for name from its
+// last checkpoint (upload ID, completed part numbers/ETags/offsets). It
+// returns the byte offset the checkpoint left off at; the caller must
+// seek its backup source stream there before writing. Actually resuming
+// is a deliberate, explicit choice by the caller; NewBackupWriter/the S3
+// driver never reach for a checkpoint on their own, since a backup name
+// is also reused across independent scheduled runs.
+//
+// Resuming is only supported with compression=NONE, cypher=NO_CYPHER
+// and integrityOpts=nil. completedPart.Size (and so Offset) is the
+// count of bytes mw itself received - plaintext bytes only when
+// nothing transforms them on the way to mw. With compression or
+// encryption enabled that's no longer true, so Offset wouldn't line up
+// with the caller's plaintext source stream; and encryption or
+// integrity hashing would additionally need its cypher nonce/counter
+// or hasher state carried forward from the checkpoint, which nothing
+// here persists. Carrying that state is the real fix if resuming
+// compressed/encrypted/integrity-checked backups is ever needed.
+func ResumeBackupWriter(name string, stg storage.Storage, opts *pb.BackupOptions,
+	keyProvider KeyProvider, integrityOpts *IntegrityOptions) (*BackupWriter, int64, error) {
+	if opts.GetCompressionType() != pb.CompressionType_COMPRESSION_TYPE_NONE ||
+		opts.GetCypher() != pb.Cypher_CYPHER_NO_CYPHER || integrityOpts != nil {
+		return nil, 0, errors.New("resuming a multipart upload is only supported with compression, cypher and integrity hashing all disabled")
+	}
+
+	driver, err := GetDriver(stg)
+	if err != nil {
+		return nil, 0, err
+	}
+	d, ok := driver.(*s3Driver)
+	if !ok {
+		return nil, 0, errors.New("resumable uploads require the s3 or s3compatible storage driver")
+	}
+	client := s3.New(d.session)
+	cp, err := readCheckpoint(client, d.bucket, name)
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "cannot read checkpoint for %q", name)
+	}
+	// maxInFlightParts uploads run concurrently, each independently
+	// retried, so completion order doesn't match part-number order: a
+	// crash can leave the checkpoint holding a later part (e.g. 3) that
+	// finished before an earlier one (2) did. Only the contiguous
+	// 1..N prefix is safe to resume from - trusting len(cp.Parts) would
+	// both reassign an already-used part number and silently drop the
+	// missing part's byte range from the upload.
+	cp.Parts = contiguousPrefix(cp.Parts)
+	mw, err := newS3MultipartWriter(client, d.bucket, name, cp.PartSize, cp)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	bw := &BackupWriter{name: name, driver: driver, writers: []io.WriteCloser{}}
+	if err := buildWriterChain(bw, mw, opts, keyProvider, integrityOpts); err != nil {
+		return nil, 0, err
+	}
+	return bw, cp.Offset(), nil
+}
+
+// Offset reports how many bytes mw had already received according to
+// the checkpoint. It only equals a plaintext offset the caller can seek
+// its source stream to when compression and cypher were both off -
+// ResumeBackupWriter enforces that - and when cp.Parts holds only the
+// contiguous prefix of completed part numbers; ResumeBackupWriter
+// ensures that too, via contiguousPrefix.
+func (cp *multipartCheckpoint) Offset() int64 {
+	var total int64
+	for _, p := range cp.Parts {
+		total += p.Size
+	}
+	return total
+}
+
+// contiguousPrefix returns the longest run of parts, sorted by Number,
+// starting at 1 with no gaps. Concurrent part uploads can complete out
+// of order, so a checkpoint's Parts is not reliably a contiguous
+// 1..len(Parts) prefix; anything after the first gap is a part that
+// completed before an earlier, still-missing one, and resuming from it
+// would reuse that later part's number while never re-uploading the
+// gap's byte range.
+func contiguousPrefix(parts []completedPart) []completedPart {
+	sorted := append([]completedPart{}, parts...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Number < sorted[j].Number })
+
+	prefix := sorted[:0:0]
+	for i, p := range sorted {
+		if p.Number != int64(i+1) {
+			break
+		}
+		prefix = append(prefix, p)
+	}
+	return prefix
+}
+
+type s3MultipartWriter struct {
+	client   *s3.S3
+	bucket   string
+	key      string
+	uploadID string
+	partSize int64
+
+	buf      *bytes.Buffer
+	nextPart int64
+
+	mu    sync.Mutex
+	parts []completedPart
+	sem   chan struct{}
+	wg    sync.WaitGroup
+	err   error
+}
+
+func newS3MultipartWriter(client *s3.S3, bucket, name string, partSize int64, resumeFrom *multipartCheckpoint) (*s3MultipartWriter, error) {
+	if partSize < minPartSize {
+		partSize = defaultPartSize
+	}
+
+	mw := &s3MultipartWriter{
+		client:   client,
+		bucket:   bucket,
+		key:      name,
+		partSize: partSize,
+		buf:      &bytes.Buffer{},
+		sem:      make(chan struct{}, maxInFlightParts),
+	}
+
+	if resumeFrom != nil {
+		// resumeFrom.Parts isn't guaranteed to be a contiguous 1..N
+		// prefix - concurrent part uploads can complete out of order -
+		// so trust only the contiguous prefix as already-completed;
+		// anything past the first gap gets re-uploaded (and its part
+		// number reused/overwritten) once Write resumes.
+		parts := contiguousPrefix(resumeFrom.Parts)
+		mw.uploadID = resumeFrom.UploadID
+		mw.partSize = resumeFrom.PartSize
+		mw.parts = append(mw.parts, parts...)
+		mw.nextPart = int64(len(parts))
+		return mw, nil
+	}
+
+	out, err := client.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(name),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot initiate multipart upload")
+	}
+	mw.uploadID = aws.StringValue(out.UploadId)
+	return mw, nil
+}
+
+func (mw *s3MultipartWriter) Write(p []byte) (int, error) {
+	total := len(p)
+	for len(p) > 0 {
+		free := int(mw.partSize) - mw.buf.Len()
+		n := len(p)
+		if n > free {
+			n = free
+		}
+		mw.buf.Write(p[:n])
+		p = p[n:]
+		if int64(mw.buf.Len()) >= mw.partSize {
+			if err := mw.uploadBufferedPart(false); err != nil {
+				return total - len(p), err
+			}
+		}
+	}
+	return total, nil
+}
+
+// Flush uploads whatever is left in the buffer as the final part. S3
+// allows the last part of a multipart upload to be smaller than
+// minPartSize, which is the only reason this is a separate path from
+// uploadBufferedPart's normal, full-size case.
+func (mw *s3MultipartWriter) Flush() error {
+	if mw.buf.Len() == 0 {
+		return nil
+	}
+	return mw.uploadBufferedPart(true)
+}
+
+func (mw *s3MultipartWriter) uploadBufferedPart(final bool) error {
+	if !final && mw.buf.Len() < minPartSize {
+		return nil
+	}
+	data := make([]byte, mw.buf.Len())
+	copy(data, mw.buf.Bytes())
+	mw.buf.Reset()
+
+	partNumber := mw.nextPart + 1
+	mw.nextPart++
+
+	mw.sem <- struct{}{}
+	mw.wg.Add(1)
+	go func() {
+		defer mw.wg.Done()
+		defer func() { <-mw.sem }()
+		etag, err := mw.uploadPartWithRetries(partNumber, data)
+		mw.mu.Lock()
+		defer mw.mu.Unlock()
+		if err != nil {
+			if mw.err == nil {
+				mw.err = err
+			}
+			return
+		}
+		mw.parts = append(mw.parts, completedPart{Number: partNumber, ETag: etag, Size: int64(len(data))})
+		mw.checkpoint()
+	}()
+	return nil
+}
+
+func (mw *s3MultipartWriter) uploadPartWithRetries(partNumber int64, data []byte) (string, error) {
+	var lastErr error
+	for attempt := 0; attempt < maxPartRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff(attempt))
+		}
+		out, err := mw.client.UploadPart(&s3.UploadPartInput{
+			Bucket:     aws.String(mw.bucket),
+			Key:        aws.String(mw.key),
+			UploadId:   aws.String(mw.uploadID),
+			PartNumber: aws.Int64(partNumber),
+			Body:       bytes.NewReader(data),
+		})
+		if err == nil {
+			return aws.StringValue(out.ETag), nil
+		}
+		lastErr = err
+	}
+	return "", errors.Wrapf(lastErr, "part %d failed after %d attempts", partNumber, maxPartRetries)
+}
+
+func backoff(attempt int) time.Duration {
+	return time.Duration(math.Pow(2, float64(attempt))) * 100 * time.Millisecond
+}
+
+// checkpoint persists the parts completed so far. Called with mw.mu held.
+func (mw *s3MultipartWriter) checkpoint() {
+	sorted := append([]completedPart{}, mw.parts...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Number < sorted[j].Number })
+	cp := multipartCheckpoint{
+		UploadID: mw.uploadID,
+		Bucket:   mw.bucket,
+		Key:      mw.key,
+		PartSize: mw.partSize,
+		Parts:    sorted,
+	}
+	// Best-effort: a failed checkpoint write only costs us a resume
+	// point, not correctness of the upload itself.
+	_ = writeCheckpoint(mw.client, mw.bucket, mw.key, &cp)
+}
+
+func (mw *s3MultipartWriter) Close() error {
+	if err := mw.Flush(); err != nil {
+		return err
+	}
+	mw.wg.Wait()
+
+	mw.mu.Lock()
+	err := mw.err
+	parts := append([]completedPart{}, mw.parts...)
+	mw.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(parts, func(i, j int) bool { return parts[i].Number < parts[j].Number })
+	completed := make([]*s3.CompletedPart, len(parts))
+	for i, p := range parts {
+		completed[i] = &s3.CompletedPart{ETag: aws.String(p.ETag), PartNumber: aws.Int64(p.Number)}
+	}
+	_, err = mw.client.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String(mw.bucket),
+		Key:      aws.String(mw.key),
+		UploadId: aws.String(mw.uploadID),
+		MultipartUpload: &s3.CompletedMultipartUpload{
+			Parts: completed,
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "cannot complete multipart upload")
+	}
+	deleteCheckpoint(mw.client, mw.bucket, mw.key)
+	return nil
+}
+
+// Abort cancels the multipart upload, releasing any parts S3 has
+// buffered server-side, and removes the checkpoint. Use this instead of
+// Close when the backup is being cancelled rather than finished.
+func (mw *s3MultipartWriter) Abort() error {
+	_, err := mw.client.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(mw.bucket),
+		Key:      aws.String(mw.key),
+		UploadId: aws.String(mw.uploadID),
+	})
+	deleteCheckpoint(mw.client, mw.bucket, mw.key)
+	if err != nil {
+		return errors.Wrap(err, "cannot abort multipart upload")
+	}
+	return nil
+}
+
+func checkpointKey(name string) string {
+	return name + CheckpointSuffix
+}
+
+func writeCheckpoint(client *s3.S3, bucket, name string, cp *multipartCheckpoint) error {
+	body, err := json.Marshal(cp)
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal checkpoint")
+	}
+	_, err = client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(checkpointKey(name)),
+		Body:   bytes.NewReader(body),
+	})
+	return err
+}
+
+func readCheckpoint(client *s3.S3, bucket, name string) (*multipartCheckpoint, error) {
+	out, err := client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(checkpointKey(name)),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "no checkpoint found for %q", name)
+	}
+	defer out.Body.Close()
+	var cp multipartCheckpoint
+	if err := json.NewDecoder(out.Body).Decode(&cp); err != nil {
+		return nil, errors.Wrap(err, "cannot decode checkpoint")
+	}
+	return &cp, nil
+}
+
+func deleteCheckpoint(client *s3.S3, bucket, name string) {
+	_, _ = client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(checkpointKey(name)),
+	})
+}
+
+var _ flusher = (*s3MultipartWriter)(nil)
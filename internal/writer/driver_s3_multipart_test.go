@@ -0,0 +1,144 @@
+package writer
+
+import (
+	"testing"
+
+	"github.com/percona/percona-backup-mongodb/internal/storage"
+	pb "github.com/percona/percona-backup-mongodb/proto/messages"
+)
+
+func TestMultipartCheckpointOffset(t *testing.T) {
+	cp := &multipartCheckpoint{
+		Parts: []completedPart{
+			{Number: 1, Size: 16 * 1024 * 1024},
+			{Number: 2, Size: 16 * 1024 * 1024},
+			{Number: 3, Size: 4 * 1024 * 1024},
+		},
+	}
+	if got, want := cp.Offset(), int64(36*1024*1024); got != want {
+		t.Fatalf("Offset() = %d, want %d", got, want)
+	}
+}
+
+// TestNewS3MultipartWriterResumesFromCheckpoint checks that resuming
+// picks up numbering and buffered state from the checkpoint instead of
+// starting a fresh upload, without touching the network (newS3MultipartWriter
+// only calls CreateMultipartUpload when resumeFrom is nil).
+func TestNewS3MultipartWriterResumesFromCheckpoint(t *testing.T) {
+	cp := &multipartCheckpoint{
+		UploadID: "upload-123",
+		PartSize: 8 * 1024 * 1024,
+		Parts: []completedPart{
+			{Number: 1, ETag: "etag-1", Size: 8 * 1024 * 1024},
+			{Number: 2, ETag: "etag-2", Size: 8 * 1024 * 1024},
+		},
+	}
+
+	mw, err := newS3MultipartWriter(nil, "bucket", "name", cp.PartSize, cp)
+	if err != nil {
+		t.Fatalf("newS3MultipartWriter: %s", err)
+	}
+	if mw.uploadID != cp.UploadID {
+		t.Errorf("uploadID = %q, want %q", mw.uploadID, cp.UploadID)
+	}
+	if mw.nextPart != int64(len(cp.Parts)) {
+		t.Errorf("nextPart = %d, want %d", mw.nextPart, len(cp.Parts))
+	}
+	if len(mw.parts) != len(cp.Parts) {
+		t.Fatalf("parts = %v, want %v", mw.parts, cp.Parts)
+	}
+	for i, p := range cp.Parts {
+		if mw.parts[i] != p {
+			t.Errorf("parts[%d] = %+v, want %+v", i, mw.parts[i], p)
+		}
+	}
+}
+
+// TestNewS3MultipartWriterResumesFromOutOfOrderCheckpoint guards against
+// a checkpoint that completed part 3 before part 2 (possible since parts
+// upload concurrently): resuming must not trust part 3 as if it were
+// part 2, since that would both skip re-uploading part 2's byte range
+// and hand the next Write part number 3 again, overwriting the real
+// part 3 once it re-completes.
+func TestNewS3MultipartWriterResumesFromOutOfOrderCheckpoint(t *testing.T) {
+	cp := &multipartCheckpoint{
+		UploadID: "upload-123",
+		PartSize: 8 * 1024 * 1024,
+		Parts: []completedPart{
+			{Number: 1, ETag: "etag-1", Size: 8 * 1024 * 1024},
+			{Number: 3, ETag: "etag-3", Size: 8 * 1024 * 1024},
+		},
+	}
+
+	mw, err := newS3MultipartWriter(nil, "bucket", "name", cp.PartSize, cp)
+	if err != nil {
+		t.Fatalf("newS3MultipartWriter: %s", err)
+	}
+	if mw.nextPart != 1 {
+		t.Errorf("nextPart = %d, want 1 (only part 1 is a contiguous prefix)", mw.nextPart)
+	}
+	if len(mw.parts) != 1 || mw.parts[0].Number != 1 {
+		t.Errorf("parts = %v, want only part 1", mw.parts)
+	}
+}
+
+func TestContiguousPrefix(t *testing.T) {
+	cases := []struct {
+		name  string
+		in    []completedPart
+		wantN []int64
+	}{
+		{name: "empty", in: nil, wantN: nil},
+		{name: "already contiguous", in: []completedPart{{Number: 1}, {Number: 2}, {Number: 3}}, wantN: []int64{1, 2, 3}},
+		{name: "gap after first", in: []completedPart{{Number: 1}, {Number: 3}}, wantN: []int64{1}},
+		{name: "unsorted with gap", in: []completedPart{{Number: 3}, {Number: 1}}, wantN: []int64{1}},
+		{name: "missing the first part", in: []completedPart{{Number: 2}, {Number: 3}}, wantN: nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := contiguousPrefix(c.in)
+			if len(got) != len(c.wantN) {
+				t.Fatalf("contiguousPrefix(%v) = %v, want numbers %v", c.in, got, c.wantN)
+			}
+			for i, n := range c.wantN {
+				if got[i].Number != n {
+					t.Errorf("contiguousPrefix(%v)[%d].Number = %d, want %d", c.in, i, got[i].Number, n)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckpointKey(t *testing.T) {
+	if got, want := checkpointKey("backup.bak"), "backup.bak"+CheckpointSuffix; got != want {
+		t.Fatalf("checkpointKey() = %q, want %q", got, want)
+	}
+}
+
+// TestResumeBackupWriterRejectsTransforms guards the invariant Offset
+// depends on: resuming is only meaningful when nothing transforms bytes
+// between the caller's plaintext and mw, since compression/encryption
+// would make the checkpointed byte count useless as a plaintext seek
+// offset, and encryption/integrity hashing would additionally need
+// nonce/counter/hasher state this package doesn't checkpoint.
+func TestResumeBackupWriterRejectsTransforms(t *testing.T) {
+	stg := storage.Storage{Type: "s3", S3: storage.S3{Bucket: "bucket"}}
+
+	cases := []struct {
+		name  string
+		opts  *pb.BackupOptions
+		integ *IntegrityOptions
+	}{
+		{name: "gzip", opts: &pb.BackupOptions{CompressionType: pb.CompressionType_COMPRESSION_TYPE_GZIP}},
+		{name: "cypher", opts: &pb.BackupOptions{Cypher: pb.Cypher_CYPHER_AES256_GCM}},
+		{name: "integrity", opts: &pb.BackupOptions{}, integ: &IntegrityOptions{}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, _, err := ResumeBackupWriter("name", stg, c.opts, nil, c.integ)
+			if err == nil {
+				t.Fatalf("expected ResumeBackupWriter to reject %s, got nil error", c.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package writer
+
+import (
+	"hash"
+	"io"
+
+	"github.com/percona/percona-backup-mongodb/internal/integrity"
+	"github.com/pkg/errors"
+)
+
+// hashCounter tees writes into a hash.Hash while counting the bytes
+// seen, so a single pass over a stream yields both its digest and size.
+type hashCounter struct {
+	h hash.Hash
+	n int64
+}
+
+func newHashCounter(alg integrity.Algorithm) (*hashCounter, error) {
+	h, err := integrity.NewHash(alg)
+	if err != nil {
+		return nil, err
+	}
+	return &hashCounter{h: h}, nil
+}
+
+func (hc *hashCounter) write(p []byte) {
+	hc.h.Write(p)
+	hc.n += int64(len(p))
+}
+
+// merkleBuilder folds a plaintext stream into integrity.ChunkSize leaf
+// hashes as it's written, one call to Write at a time regardless of how
+// the caller chunks their own writes.
+type merkleBuilder struct {
+	alg    integrity.Algorithm
+	buf    []byte
+	leaves [][]byte
+}
+
+func newMerkleBuilder(alg integrity.Algorithm) *merkleBuilder {
+	return &merkleBuilder{alg: alg, buf: make([]byte, 0, integrity.ChunkSize)}
+}
+
+func (b *merkleBuilder) write(p []byte) error {
+	for len(p) > 0 {
+		free := integrity.ChunkSize - len(b.buf)
+		n := len(p)
+		if n > free {
+			n = free
+		}
+		b.buf = append(b.buf, p[:n]...)
+		p = p[n:]
+		if len(b.buf) == integrity.ChunkSize {
+			if err := b.flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (b *merkleBuilder) flush() error {
+	h, err := integrity.NewHash(b.alg)
+	if err != nil {
+		return err
+	}
+	h.Write(b.buf)
+	b.leaves = append(b.leaves, h.Sum(nil))
+	b.buf = b.buf[:0]
+	return nil
+}
+
+func (b *merkleBuilder) root() ([]byte, error) {
+	if len(b.buf) > 0 {
+		if err := b.flush(); err != nil {
+			return nil, err
+		}
+	}
+	return integrity.MerkleRoot(b.alg, b.leaves)
+}
+
+// digestTap wraps an io.WriteCloser and mirrors every write into a
+// hashCounter, so we can measure the ciphertext/compressed stream that
+// actually reaches storage without the compressor or encryptor needing
+// to know about hashing at all.
+type digestTap struct {
+	dst io.WriteCloser
+	hc  *hashCounter
+}
+
+func (t *digestTap) Write(p []byte) (int, error) {
+	n, err := t.dst.Write(p)
+	if n > 0 {
+		t.hc.write(p[:n])
+	}
+	return n, err
+}
+
+func (t *digestTap) Close() error {
+	return t.dst.Close()
+}
+
+// backupIntegrity accumulates everything NewBackupWriter's manifest
+// needs: digests/sizes of the stream before and after compression plus
+// encryption, and a Merkle root over the plaintext chunks.
+type backupIntegrity struct {
+	alg         integrity.Algorithm
+	manifestKey []byte
+	plain       *hashCounter
+	merkle      *merkleBuilder
+	cipherTap   *digestTap
+}
+
+func newBackupIntegrity(alg integrity.Algorithm, manifestKey []byte, dst io.WriteCloser) (*backupIntegrity, io.WriteCloser, error) {
+	plain, err := newHashCounter(alg)
+	if err != nil {
+		return nil, nil, err
+	}
+	cipherHC, err := newHashCounter(alg)
+	if err != nil {
+		return nil, nil, err
+	}
+	tap := &digestTap{dst: dst, hc: cipherHC}
+	return &backupIntegrity{
+		alg:         alg,
+		manifestKey: manifestKey,
+		plain:       plain,
+		merkle:      newMerkleBuilder(alg),
+		cipherTap:   tap,
+	}, tap, nil
+}
+
+func (bi *backupIntegrity) observePlain(p []byte) error {
+	bi.plain.write(p)
+	return bi.merkle.write(p)
+}
+
+// manifest builds the signed integrity.Manifest for name once the
+// writer chain has been fully closed (so cipherTap has seen every byte
+// that reached storage).
+func (bi *backupIntegrity) manifest(name string) (*integrity.Manifest, error) {
+	root, err := bi.merkle.root()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot compute Merkle root")
+	}
+	m := &integrity.Manifest{
+		Name:             name,
+		Algorithm:        bi.alg,
+		OriginalSize:     bi.plain.n,
+		CompressedSize:   bi.cipherTap.hc.n,
+		PlaintextDigest:  bi.plain.h.Sum(nil),
+		PlaintextMerkle:  root,
+		CiphertextDigest: bi.cipherTap.hc.h.Sum(nil),
+		Shards: []integrity.ShardDigest{
+			{Name: name, Size: bi.cipherTap.hc.n, Digest: bi.cipherTap.hc.h.Sum(nil)},
+		},
+	}
+	if len(bi.manifestKey) > 0 {
+		if err := m.Sign(bi.manifestKey); err != nil {
+			return nil, errors.Wrap(err, "cannot sign manifest")
+		}
+	}
+	return m, nil
+}
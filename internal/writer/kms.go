@@ -0,0 +1,50 @@
+package writer
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/percona/percona-backup-mongodb/internal/awsutils"
+	"github.com/percona/percona-backup-mongodb/internal/storage"
+	"github.com/pkg/errors"
+)
+
+// awsKMSKeyProvider implements KeyProvider via envelope encryption: the
+// DEK is generated locally and wrapped under the configured CMK by
+// KMS's Encrypt call, which receives the DEK itself; only the wrapped
+// DEK is stored, and Decrypt recovers the DEK from it.
+type awsKMSKeyProvider struct {
+	client *kms.KMS
+	keyID  string
+}
+
+// NewAWSKMSKeyProvider builds a KeyProvider backed by AWS KMS key keyID,
+// reusing the same session configuration as the S3 storage driver.
+func NewAWSKMSKeyProvider(stg storage.S3, keyID string) (*awsKMSKeyProvider, error) {
+	session, err := awsutils.GetAWSSessionFromStorage(stg)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get an AWS session for KMS")
+	}
+	return &awsKMSKeyProvider{client: kms.New(session), keyID: keyID}, nil
+}
+
+func (p *awsKMSKeyProvider) WrapKey(dek []byte) ([]byte, error) {
+	out, err := p.client.Encrypt(&kms.EncryptInput{
+		KeyId:     aws.String(p.keyID),
+		Plaintext: dek,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "KMS Encrypt failed")
+	}
+	return out.CiphertextBlob, nil
+}
+
+func (p *awsKMSKeyProvider) UnwrapKey(wrapped []byte) ([]byte, error) {
+	out, err := p.client.Decrypt(&kms.DecryptInput{
+		KeyId:          aws.String(p.keyID),
+		CiphertextBlob: wrapped,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "KMS Decrypt failed")
+	}
+	return out.Plaintext, nil
+}
@@ -1,28 +1,32 @@
 package writer
 
 import (
-	"compress/gzip"
+	"context"
 	"fmt"
 	"io"
-	"os"
-	"path"
-	"strings"
-	"sync"
+	"runtime"
+	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/golang/snappy"
-	"github.com/percona/percona-backup-mongodb/internal/awsutils"
+	"github.com/klauspost/pgzip"
+	"github.com/percona/percona-backup-mongodb/internal/integrity"
 	"github.com/percona/percona-backup-mongodb/internal/storage"
 	pb "github.com/percona/percona-backup-mongodb/proto/messages"
 	"github.com/pierrec/lz4"
 	"github.com/pkg/errors"
 )
 
+// defaultGzipBlockSize is the size of the block pgzip compresses
+// independently in each worker goroutine when BackupOptions.GzipBlockSize
+// is left at 0. Larger blocks compress slightly better but give
+// coarser-grained parallelism.
+const defaultGzipBlockSize = 1 << 20 // 1 MiB
+
 type BackupWriter struct {
+	name      string
+	driver    StorageDriver
 	writers   []io.WriteCloser
-	wg        *sync.WaitGroup
-	lastError error
+	integrity *backupIntegrity
 }
 
 type flusher interface {
@@ -41,56 +45,138 @@ func (bw *BackupWriter) Close() error {
 			return fmt.Errorf("error closing writer %d: %s", i, err)
 		}
 	}
-	bw.wg.Wait()
+
+	if bw.integrity != nil {
+		if err := bw.writeManifest(); err != nil {
+			return errors.Wrap(err, "cannot write integrity manifest")
+		}
+	}
 	return nil
 }
 
+func (bw *BackupWriter) writeManifest() error {
+	m, err := bw.integrity.manifest(bw.name)
+	if err != nil {
+		return err
+	}
+	m.CreatedUnix = time.Now().Unix()
+
+	body, err := m.Marshal()
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal manifest")
+	}
+	mw, err := bw.driver.NewWriter(context.Background(), bw.name+integrity.ManifestSuffix)
+	if err != nil {
+		return errors.Wrap(err, "cannot open manifest for writing")
+	}
+	if _, err := mw.Write(body); err != nil {
+		mw.Close()
+		return errors.Wrap(err, "cannot write manifest")
+	}
+	return mw.Close()
+}
+
 func (bw *BackupWriter) Write(p []byte) (int, error) {
-	return bw.writers[len(bw.writers)-1].Write(p)
+	n, err := bw.writers[len(bw.writers)-1].Write(p)
+	if n > 0 && bw.integrity != nil {
+		if ierr := bw.integrity.observePlain(p[:n]); ierr != nil {
+			return n, ierr
+		}
+	}
+	return n, err
+}
+
+// IntegrityOptions turns on manifest generation for a backup. A nil
+// *IntegrityOptions passed to NewBackupWriter disables it entirely (no
+// hashing overhead, no manifest object written), matching a plain
+// restore of a backup written by an older agent.
+type IntegrityOptions struct {
+	// Algorithm defaults to integrity.SHA256 when empty.
+	Algorithm integrity.Algorithm
+	// ManifestKey HMAC-signs the manifest. Leave nil to skip signing.
+	ManifestKey []byte
 }
 
-func NewBackupWriter(name string, stg storage.Storage, compressionType pb.CompressionType,
-	cypher pb.Cypher) (*BackupWriter, error) {
+// NewBackupWriter builds the writer chain for a backup: a storage-backed
+// sink, followed by an optional encryptor, followed by an optional
+// compressor - so a caller's Write call compresses before encrypting.
+// keyProvider is only consulted when cypher != CYPHER_NO_CYPHER
+// and may be nil otherwise. opts.GzipParallelism and opts.GzipBlockSize
+// only apply to COMPRESSION_TYPE_GZIP: GzipParallelism 0 means
+// runtime.NumCPU(), 1 preserves the historical single-threaded behavior;
+// GzipBlockSize 0 means defaultGzipBlockSize. See IntegrityOptions for
+// integrityOpts.
+func NewBackupWriter(name string, stg storage.Storage, opts *pb.BackupOptions,
+	keyProvider KeyProvider, integrityOpts *IntegrityOptions) (*BackupWriter, error) {
+	driver, err := GetDriver(stg)
+	if err != nil {
+		return nil, err
+	}
+	dw, err := driver.NewWriter(context.Background(), name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open %q for writing", name)
+	}
+
 	bw := &BackupWriter{
+		name:    name,
+		driver:  driver,
 		writers: []io.WriteCloser{},
-		wg:      &sync.WaitGroup{},
 	}
+	if err := buildWriterChain(bw, dw, opts, keyProvider, integrityOpts); err != nil {
+		return nil, err
+	}
+	return bw, nil
+}
 
-	switch strings.ToLower(stg.Type) {
-	case "filesystem":
-		filepath := path.Join(stg.Filesystem.Path, name)
-		fw, err := os.Create(filepath)
+// buildWriterChain appends dw to bw.writers and layers integrity
+// hashing, encryption and compression on top of it, in that order (so
+// the plaintext is compressed before it's encrypted) - the same chain
+// NewBackupWriter builds around a fresh storage writer, and what
+// ResumeBackupWriter builds around a resumed one. ResumeBackupWriter
+// only accepts options with compression, cypher and integrity hashing
+// all off, so for a resumed upload the chain is just the storage sink.
+func buildWriterChain(bw *BackupWriter, dw io.WriteCloser, opts *pb.BackupOptions,
+	keyProvider KeyProvider, integrityOpts *IntegrityOptions) error {
+	if integrityOpts != nil {
+		bi, tap, err := newBackupIntegrity(integrityOpts.Algorithm, integrityOpts.ManifestKey, dw)
 		if err != nil {
-			return nil, errors.Wrapf(err, "Cannot create destination file: %s", filepath)
+			return errors.Wrap(err, "cannot set up integrity hashing")
 		}
-		bw.writers = append(bw.writers, fw)
-	case "s3":
-		awsSession, err := awsutils.GetAWSSessionFromStorage(stg.S3)
+		bw.integrity = bi
+		dw = tap
+	}
+	bw.writers = append(bw.writers, dw)
+
+	// Cypher wraps the storage/integrity sink and compression wraps
+	// cypher, so the data flow is plaintext -> compress -> encrypt ->
+	// storage: encrypting before compressing would feed pgzip/lz4/
+	// snappy high-entropy AES-GCM ciphertext, which is incompressible,
+	// paying the full compression cost for ~0% size reduction.
+	if opts.GetCypher() != pb.Cypher_CYPHER_NO_CYPHER {
+		cw, err := NewCypherWriter(bw.writers[len(bw.writers)-1], CypherOptions{
+			Cypher:      opts.GetCypher(),
+			KeyProvider: keyProvider,
+		})
 		if err != nil {
-			return nil, errors.Wrap(err, "cannot get an AWS session")
+			return errors.Wrap(err, "cannot set up encryption")
 		}
-		// s3.Uploader runs synchronously and receives an io.Reader but here, we are implementing
-		// writers so, we need to create an io.Pipe and run uploader.Upload in a go-routine
-		pr, pw := io.Pipe()
-		go func() {
-			uploader := s3manager.NewUploader(awsSession)
-			bw.wg.Add(1)
-			_, bw.lastError = uploader.Upload(&s3manager.UploadInput{
-				Bucket: aws.String(stg.S3.Bucket),
-				Key:    aws.String(name),
-				Body:   pr,
-			})
-			// make Close() to wait until the upload has finished
-			bw.wg.Done()
-		}()
-		bw.writers = append(bw.writers, pw)
-	default:
-		return nil, fmt.Errorf("Don't know how to handle %q storage type", stg.Type)
+		bw.writers = append(bw.writers, cw)
 	}
 
-	switch compressionType {
+	switch opts.GetCompressionType() {
 	case pb.CompressionType_COMPRESSION_TYPE_GZIP:
-		gzw := gzip.NewWriter(bw.writers[len(bw.writers)-1])
+		parallelism := int(opts.GetGzipParallelism())
+		if parallelism == 0 {
+			parallelism = runtime.NumCPU()
+		}
+		blockSize := int(opts.GetGzipBlockSize())
+		if blockSize == 0 {
+			blockSize = defaultGzipBlockSize
+		}
+		gzw := pgzip.NewWriter(bw.writers[len(bw.writers)-1])
+		if err := gzw.SetConcurrency(blockSize, parallelism); err != nil {
+			return errors.Wrap(err, "cannot configure pgzip concurrency")
+		}
 		bw.writers = append(bw.writers, gzw)
 	case pb.CompressionType_COMPRESSION_TYPE_LZ4:
 		lz4w := lz4.NewWriter(bw.writers[len(bw.writers)-1])
@@ -100,13 +186,8 @@ func NewBackupWriter(name string, stg storage.Storage, compressionType pb.Compre
 		bw.writers = append(bw.writers, snappyw)
 	}
 
-	switch cypher {
-	case pb.Cypher_CYPHER_NO_CYPHER:
-		//TODO: Add cyphers
-	}
-
 	if len(bw.writers) == 0 {
-		return nil, fmt.Errorf("there are no backup writers")
+		return fmt.Errorf("there are no backup writers")
 	}
-	return bw, nil
+	return nil
 }
@@ -0,0 +1,50 @@
+// source: backup_options.proto
+
+package messages
+
+// BackupOptions configures how a single backup is written: its
+// compression and encryption scheme, and the concurrency pgzip uses
+// when CompressionType is COMPRESSION_TYPE_GZIP.
+type BackupOptions struct {
+	CompressionType CompressionType `protobuf:"varint,1,opt,name=compression_type,json=compressionType,proto3,enum=messages.CompressionType" json:"compression_type,omitempty"`
+	Cypher          Cypher          `protobuf:"varint,2,opt,name=cypher,proto3,enum=messages.Cypher" json:"cypher,omitempty"`
+
+	// GzipParallelism is the number of pgzip worker goroutines. 0 means
+	// runtime.NumCPU(), 1 preserves single-threaded compress/gzip
+	// behavior. Ignored unless CompressionType is COMPRESSION_TYPE_GZIP.
+	GzipParallelism int32 `protobuf:"varint,3,opt,name=gzip_parallelism,json=gzipParallelism,proto3" json:"gzip_parallelism,omitempty"`
+
+	// GzipBlockSize is the size in bytes of the block pgzip compresses
+	// independently in each worker goroutine. 0 means the writer
+	// package's built-in default. Ignored unless CompressionType is
+	// COMPRESSION_TYPE_GZIP.
+	GzipBlockSize int32 `protobuf:"varint,4,opt,name=gzip_block_size,json=gzipBlockSize,proto3" json:"gzip_block_size,omitempty"`
+}
+
+func (m *BackupOptions) GetCompressionType() CompressionType {
+	if m != nil {
+		return m.CompressionType
+	}
+	return CompressionType_COMPRESSION_TYPE_NONE
+}
+
+func (m *BackupOptions) GetCypher() Cypher {
+	if m != nil {
+		return m.Cypher
+	}
+	return Cypher_CYPHER_NO_CYPHER
+}
+
+func (m *BackupOptions) GetGzipParallelism() int32 {
+	if m != nil {
+		return m.GzipParallelism
+	}
+	return 0
+}
+
+func (m *BackupOptions) GetGzipBlockSize() int32 {
+	if m != nil {
+		return m.GzipBlockSize
+	}
+	return 0
+}
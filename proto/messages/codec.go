@@ -0,0 +1,41 @@
+package messages
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// schedulerJSONCodecName is the grpc content-subtype SchedulerService
+// calls are forced onto (scheduler_grpc.pb.go's client methods set it
+// via grpc.CallContentSubtype on every call), registered under its own
+// name rather than overriding grpc-go's built-in "proto" codec. Any
+// other gRPC traffic in the process - agent<->coordinator RPC, anything
+// using real proto.Message types - keeps using the stock protobuf
+// codec untouched.
+const schedulerJSONCodecName = "pbm-scheduler-json"
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// jsonCodec marshals the SchedulerService request/response types with
+// encoding/json. Those types are plain structs written by hand rather
+// than by protoc-gen-go: they don't implement proto.Message/
+// ProtoReflect, which the stock protobuf codec requires, so they can't
+// go through it. Every field already carries a json tag (kept from the
+// .proto field names), so encoding/json is a drop-in wire format for
+// just this service.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return schedulerJSONCodecName
+}
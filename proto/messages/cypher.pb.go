@@ -0,0 +1,65 @@
+// source: cypher.proto
+
+package messages
+
+import "fmt"
+
+// Cypher selects the at-rest encryption scheme applied to a backup
+// stream, after whatever CompressionType is configured has already
+// compressed it (encrypting first would make the ciphertext opaque to
+// the compressor).
+type Cypher int32
+
+const (
+	Cypher_CYPHER_NO_CYPHER  Cypher = 0
+	Cypher_CYPHER_AES256_GCM Cypher = 1
+)
+
+var Cypher_name = map[int32]string{
+	0: "CYPHER_NO_CYPHER",
+	1: "CYPHER_AES256_GCM",
+}
+
+var Cypher_value = map[string]int32{
+	"CYPHER_NO_CYPHER":  0,
+	"CYPHER_AES256_GCM": 1,
+}
+
+func (c Cypher) String() string {
+	if s, ok := Cypher_name[int32(c)]; ok {
+		return s
+	}
+	return fmt.Sprintf("Cypher(%d)", int32(c))
+}
+
+// CompressionType selects how a backup stream is compressed before it
+// reaches storage.
+type CompressionType int32
+
+const (
+	CompressionType_COMPRESSION_TYPE_NONE   CompressionType = 0
+	CompressionType_COMPRESSION_TYPE_GZIP   CompressionType = 1
+	CompressionType_COMPRESSION_TYPE_LZ4    CompressionType = 2
+	CompressionType_COMPRESSION_TYPE_SNAPPY CompressionType = 3
+)
+
+var CompressionType_name = map[int32]string{
+	0: "COMPRESSION_TYPE_NONE",
+	1: "COMPRESSION_TYPE_GZIP",
+	2: "COMPRESSION_TYPE_LZ4",
+	3: "COMPRESSION_TYPE_SNAPPY",
+}
+
+var CompressionType_value = map[string]int32{
+	"COMPRESSION_TYPE_NONE":   0,
+	"COMPRESSION_TYPE_GZIP":   1,
+	"COMPRESSION_TYPE_LZ4":    2,
+	"COMPRESSION_TYPE_SNAPPY": 3,
+}
+
+func (c CompressionType) String() string {
+	if s, ok := CompressionType_name[int32(c)]; ok {
+		return s
+	}
+	return fmt.Sprintf("CompressionType(%d)", int32(c))
+}
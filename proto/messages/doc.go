@@ -0,0 +1,9 @@
+// Package messages holds the wire types described by the .proto files
+// in this directory (backup_options.proto, cypher.proto,
+// scheduler.proto). The *.pb.go files are hand-written to mirror the
+// shape protoc-gen-go would generate for them, not actual protoc-gen-go
+// output: they don't implement proto.Message/ProtoReflect, so they
+// can't be marshaled by grpc-go's stock protobuf codec. SchedulerService
+// traffic is carried over the JSON codec registered in codec.go instead;
+// see its doc comment for why.
+package messages
@@ -0,0 +1,89 @@
+// source: scheduler.proto
+
+package messages
+
+// ListSchedulesRequest takes no filters: the coordinator always wants
+// the full set of registered jobs.
+type ListSchedulesRequest struct{}
+
+type ListSchedulesResponse struct {
+	Schedules []*ScheduleStatus `protobuf:"bytes,1,rep,name=schedules,proto3" json:"schedules,omitempty"`
+}
+
+func (m *ListSchedulesResponse) GetSchedules() []*ScheduleStatus {
+	if m != nil {
+		return m.Schedules
+	}
+	return nil
+}
+
+// ScheduleStatus is a point-in-time snapshot of one scheduled job.
+type ScheduleStatus struct {
+	Name    string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Paused  bool   `protobuf:"varint,2,opt,name=paused,proto3" json:"paused,omitempty"`
+	Running bool   `protobuf:"varint,3,opt,name=running,proto3" json:"running,omitempty"`
+
+	// NextRunUnix/LastRunUnix are 0 when there is no next/last run yet.
+	NextRunUnix int64 `protobuf:"varint,4,opt,name=next_run_unix,json=nextRunUnix,proto3" json:"next_run_unix,omitempty"`
+	LastRunUnix int64 `protobuf:"varint,5,opt,name=last_run_unix,json=lastRunUnix,proto3" json:"last_run_unix,omitempty"`
+
+	// LastError is empty when the last run succeeded or none has run yet.
+	LastError string `protobuf:"bytes,6,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+}
+
+func (m *ScheduleStatus) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ScheduleStatus) GetPaused() bool {
+	if m != nil {
+		return m.Paused
+	}
+	return false
+}
+
+func (m *ScheduleStatus) GetRunning() bool {
+	if m != nil {
+		return m.Running
+	}
+	return false
+}
+
+func (m *ScheduleStatus) GetNextRunUnix() int64 {
+	if m != nil {
+		return m.NextRunUnix
+	}
+	return 0
+}
+
+func (m *ScheduleStatus) GetLastRunUnix() int64 {
+	if m != nil {
+		return m.LastRunUnix
+	}
+	return 0
+}
+
+func (m *ScheduleStatus) GetLastError() string {
+	if m != nil {
+		return m.LastError
+	}
+	return ""
+}
+
+type ScheduleNameRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+}
+
+func (m *ScheduleNameRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+// ScheduleActionResponse is returned by every mutating RPC. It carries
+// no fields today; a non-nil response with a nil error means success.
+type ScheduleActionResponse struct{}
@@ -0,0 +1,178 @@
+// Hand-written to mirror protoc-gen-go-grpc output; see doc.go.
+// source: scheduler.proto
+
+package messages
+
+import (
+	context "context"
+
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// SchedulerServiceClient is the client API for SchedulerService.
+type SchedulerServiceClient interface {
+	ListSchedules(ctx context.Context, in *ListSchedulesRequest, opts ...grpc.CallOption) (*ListSchedulesResponse, error)
+	PauseSchedule(ctx context.Context, in *ScheduleNameRequest, opts ...grpc.CallOption) (*ScheduleActionResponse, error)
+	ResumeSchedule(ctx context.Context, in *ScheduleNameRequest, opts ...grpc.CallOption) (*ScheduleActionResponse, error)
+	TriggerScheduleNow(ctx context.Context, in *ScheduleNameRequest, opts ...grpc.CallOption) (*ScheduleActionResponse, error)
+}
+
+type schedulerServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewSchedulerServiceClient wraps cc for the SchedulerService RPCs.
+func NewSchedulerServiceClient(cc grpc.ClientConnInterface) SchedulerServiceClient {
+	return &schedulerServiceClient{cc}
+}
+
+func (c *schedulerServiceClient) ListSchedules(ctx context.Context, in *ListSchedulesRequest, opts ...grpc.CallOption) (*ListSchedulesResponse, error) {
+	out := new(ListSchedulesResponse)
+	opts = append(opts, grpc.CallContentSubtype(schedulerJSONCodecName))
+	err := c.cc.Invoke(ctx, "/messages.SchedulerService/ListSchedules", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *schedulerServiceClient) PauseSchedule(ctx context.Context, in *ScheduleNameRequest, opts ...grpc.CallOption) (*ScheduleActionResponse, error) {
+	out := new(ScheduleActionResponse)
+	opts = append(opts, grpc.CallContentSubtype(schedulerJSONCodecName))
+	err := c.cc.Invoke(ctx, "/messages.SchedulerService/PauseSchedule", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *schedulerServiceClient) ResumeSchedule(ctx context.Context, in *ScheduleNameRequest, opts ...grpc.CallOption) (*ScheduleActionResponse, error) {
+	out := new(ScheduleActionResponse)
+	opts = append(opts, grpc.CallContentSubtype(schedulerJSONCodecName))
+	err := c.cc.Invoke(ctx, "/messages.SchedulerService/ResumeSchedule", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *schedulerServiceClient) TriggerScheduleNow(ctx context.Context, in *ScheduleNameRequest, opts ...grpc.CallOption) (*ScheduleActionResponse, error) {
+	out := new(ScheduleActionResponse)
+	opts = append(opts, grpc.CallContentSubtype(schedulerJSONCodecName))
+	err := c.cc.Invoke(ctx, "/messages.SchedulerService/TriggerScheduleNow", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SchedulerServiceServer is the server API for SchedulerService.
+type SchedulerServiceServer interface {
+	ListSchedules(context.Context, *ListSchedulesRequest) (*ListSchedulesResponse, error)
+	PauseSchedule(context.Context, *ScheduleNameRequest) (*ScheduleActionResponse, error)
+	ResumeSchedule(context.Context, *ScheduleNameRequest) (*ScheduleActionResponse, error)
+	TriggerScheduleNow(context.Context, *ScheduleNameRequest) (*ScheduleActionResponse, error)
+}
+
+// UnimplementedSchedulerServiceServer can be embedded in an
+// implementation to get forward-compatible behavior: new methods added
+// to SchedulerServiceServer return codes.Unimplemented until overridden.
+type UnimplementedSchedulerServiceServer struct{}
+
+func (UnimplementedSchedulerServiceServer) ListSchedules(context.Context, *ListSchedulesRequest) (*ListSchedulesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListSchedules not implemented")
+}
+
+func (UnimplementedSchedulerServiceServer) PauseSchedule(context.Context, *ScheduleNameRequest) (*ScheduleActionResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method PauseSchedule not implemented")
+}
+
+func (UnimplementedSchedulerServiceServer) ResumeSchedule(context.Context, *ScheduleNameRequest) (*ScheduleActionResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ResumeSchedule not implemented")
+}
+
+func (UnimplementedSchedulerServiceServer) TriggerScheduleNow(context.Context, *ScheduleNameRequest) (*ScheduleActionResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method TriggerScheduleNow not implemented")
+}
+
+// RegisterSchedulerServiceServer registers srv against s.
+func RegisterSchedulerServiceServer(s grpc.ServiceRegistrar, srv SchedulerServiceServer) {
+	s.RegisterService(&SchedulerService_ServiceDesc, srv)
+}
+
+func _SchedulerService_ListSchedules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSchedulesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SchedulerServiceServer).ListSchedules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/messages.SchedulerService/ListSchedules"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SchedulerServiceServer).ListSchedules(ctx, req.(*ListSchedulesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SchedulerService_PauseSchedule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScheduleNameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SchedulerServiceServer).PauseSchedule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/messages.SchedulerService/PauseSchedule"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SchedulerServiceServer).PauseSchedule(ctx, req.(*ScheduleNameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SchedulerService_ResumeSchedule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScheduleNameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SchedulerServiceServer).ResumeSchedule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/messages.SchedulerService/ResumeSchedule"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SchedulerServiceServer).ResumeSchedule(ctx, req.(*ScheduleNameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SchedulerService_TriggerScheduleNow_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScheduleNameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SchedulerServiceServer).TriggerScheduleNow(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/messages.SchedulerService/TriggerScheduleNow"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SchedulerServiceServer).TriggerScheduleNow(ctx, req.(*ScheduleNameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// SchedulerService_ServiceDesc is the grpc.ServiceDesc for SchedulerService,
+// used by RegisterSchedulerServiceServer and NewSchedulerServiceClient.
+var SchedulerService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "messages.SchedulerService",
+	HandlerType: (*SchedulerServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "ListSchedules", Handler: _SchedulerService_ListSchedules_Handler},
+		{MethodName: "PauseSchedule", Handler: _SchedulerService_PauseSchedule_Handler},
+		{MethodName: "ResumeSchedule", Handler: _SchedulerService_ResumeSchedule_Handler},
+		{MethodName: "TriggerScheduleNow", Handler: _SchedulerService_TriggerScheduleNow_Handler},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "scheduler.proto",
+}